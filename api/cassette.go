@@ -0,0 +1,239 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"unicode/utf8"
+)
+
+const redacted = "REDACTED"
+
+// Interaction is a single request/response pair stored in a cassette.
+type Interaction struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	RequestHeader http.Header `json:"request_header,omitempty"`
+	RequestBody   string      `json:"request_body,omitempty"`
+	Status        int         `json:"status"`
+	Header        http.Header `json:"header,omitempty"`
+	// Body holds text responses, BinaryBody anything that is not valid utf8
+	// such as images.
+	Body       string `json:"body,omitempty"`
+	BinaryBody []byte `json:"binary_body,omitempty"`
+}
+
+// signerLine records the account a cassette was recorded with, so replays
+// start signed in as it.
+type signerLine struct {
+	Signer *Signer `json:"signer"`
+}
+
+func (i *Interaction) key() string {
+	return i.Method + " " + i.URL + " " + i.RequestBody
+}
+
+func (i *Interaction) response(req *http.Request) *http.Response {
+	body := []byte(i.Body)
+	if i.BinaryBody != nil {
+		body = i.BinaryBody
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Status, http.StatusText(i.Status)),
+		StatusCode:    i.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// redactBody removes the signer uuid from JSON request payloads so cassettes
+// can be shared without leaking posting credentials.
+func redactBody(body []byte) string {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return string(body)
+	}
+	if _, ok := payload["signer_uuid"]; !ok {
+		return string(body)
+	}
+	payload["signer_uuid"] = redacted
+	d, _ := json.Marshal(payload)
+	return string(d)
+}
+
+// readRequestBody returns the redacted request body and restores it on the
+// request so it can still be sent.
+func readRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	d, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(d))
+	return redactBody(d), nil
+}
+
+// Recorder is an http.RoundTripper that writes every request and response it
+// sees to a JSONL cassette. The api_key header and signer uuids are redacted.
+type Recorder struct {
+	next http.RoundTripper
+	mu   sync.Mutex
+	f    *os.File
+	enc  *json.Encoder
+}
+
+// NewRecorder creates the cassette at path and records traffic sent through
+// next, or http.DefaultTransport if next is nil.
+func NewRecorder(path string, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{next: next, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := req.Header.Clone()
+	if header.Get("api_key") != "" {
+		header.Set("api_key", redacted)
+	}
+	i := &Interaction{
+		Method:        req.Method,
+		URL:           req.URL.String(),
+		RequestHeader: header,
+		RequestBody:   reqBody,
+		Status:        res.StatusCode,
+		Header:        res.Header.Clone(),
+	}
+	if utf8.Valid(body) {
+		i.Body = string(body)
+	} else {
+		i.BinaryBody = body
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(i); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RecordSigner writes the account the session is signed in as to the
+// cassette, with its uuid redacted like request payloads.
+func (r *Recorder) RecordSigner(s *Signer) error {
+	redactedSigner := *s
+	redactedSigner.UUID = redacted
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(signerLine{Signer: &redactedSigner})
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+// Replayer is an http.RoundTripper that serves responses from a cassette
+// without touching the network. Identical requests are answered in the order
+// they were recorded, repeating the last response once exhausted.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions map[string][]*Interaction
+	signer       *Signer
+}
+
+// LoadCassette reads a cassette written by a Recorder.
+func LoadCassette(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := &Replayer{interactions: make(map[string][]*Interaction)}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var sl signerLine
+		if err := json.Unmarshal(sc.Bytes(), &sl); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s line %d: %w", path, line, err)
+		}
+		if sl.Signer != nil {
+			r.signer = sl.Signer
+			continue
+		}
+		i := &Interaction{}
+		if err := json.Unmarshal(sc.Bytes(), i); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s line %d: %w", path, line, err)
+		}
+		r.interactions[i.key()] = append(r.interactions[i.key()], i)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Signer returns the account the cassette was recorded with, or nil if it
+// was recorded signed out.
+func (r *Replayer) Signer() *Signer {
+	return r.signer
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	k := (&Interaction{Method: req.Method, URL: req.URL.String(), RequestBody: reqBody}).key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queue := r.interactions[k]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	i := queue[0]
+	if len(queue) > 1 {
+		r.interactions[k] = queue[1:]
+	}
+	return i.response(req), nil
+}
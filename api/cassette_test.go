@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/treethought/tofui/api/neynartest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	c, srv := newTestClient(t)
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+
+	rec, err := NewRecorder(path, srv.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.c = &http.Client{Transport: rec}
+
+	signer := &Signer{FID: 1, UUID: neynartest.SignerUUID}
+	req := &FeedRequest{FeedType: "following", FID: 1, ViewerFID: 1}
+	recorded, err := c.GetFeed(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.React(signer, testCastHash, Like); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), neynartest.APIKey) {
+		t.Error("cassette contains the api key")
+	}
+	if strings.Contains(string(data), neynartest.SignerUUID) {
+		t.Error("cassette contains the signer uuid")
+	}
+
+	srv.Close()
+	rp, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.c = &http.Client{Transport: rp}
+
+	replayed, err := c.GetFeed(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed.Casts) != len(recorded.Casts) {
+		t.Fatalf("expected %d replayed casts, got %d", len(recorded.Casts), len(replayed.Casts))
+	}
+	for i := range recorded.Casts {
+		if recorded.Casts[i].Hash != replayed.Casts[i].Hash {
+			t.Errorf("cast %d: expected %s, got %s", i, recorded.Casts[i].Hash, replayed.Casts[i].Hash)
+		}
+	}
+	if err := c.React(signer, testCastHash, Like); err != nil {
+		t.Errorf("expected replayed reaction: %v", err)
+	}
+
+	if _, err := c.GetNotifications(1); err == nil {
+		t.Error("expected error for request missing from cassette")
+	}
+}
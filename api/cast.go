@@ -1,10 +1,9 @@
 package api
 
 import (
-	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 )
 
@@ -86,11 +85,11 @@ func (c *Client) PostCast(signer *Signer, text, parent, channel string, parent_f
 		ChannelID:       channel,
 		ParentAuthorFID: parent_fid,
 	}
-	log.Println("posting cast: ", text)
+	slog.Debug("posting cast", "fid", signer.FID)
 
 	var resp PostCastResponse
-	if err := c.doPostInto(context.TODO(), "/cast", payload, &resp); err != nil {
-		log.Println("failed to post cast: ", err)
+	if err := c.doPostInto(c.ctx, "/cast", payload, &resp); err != nil {
+		slog.Error("failed to post cast", "error", err)
 		return nil, err
 	}
 	if !resp.Success {
@@ -122,7 +121,7 @@ func (c *Client) GetCastWithReplies(signer *Signer, hash string) (*Cast, error)
 	}
 
 	var resp ConversationResponse
-	if err := c.doRequestInto(context.TODO(), path, &resp, opts...); err != nil {
+	if err := c.doRequestInto(c.ctx, path, &resp, opts...); err != nil {
 		return nil, err
 	}
 	if resp.Conversation == nil {
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/treethought/tofui/api/neynartest"
+)
+
+const testCastHash = "0x0000000000000000000000000000000000000a01"
+
+func TestGetCastWithReplies(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	cast, err := c.GetCastWithReplies(&Signer{FID: 1}, testCastHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cast.Author.Username != "alice" {
+		t.Errorf("expected cast by alice, got %s", cast.Author.Username)
+	}
+	if len(cast.DirectReplies) != 2 {
+		t.Fatalf("expected 2 direct replies, got %d", len(cast.DirectReplies))
+	}
+	for _, r := range cast.DirectReplies {
+		if r.ParentHash != testCastHash {
+			t.Errorf("reply %s has parent %s", r.Hash, r.ParentHash)
+		}
+	}
+}
+
+func TestGetCastWithRepliesNotFound(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	_, err := c.GetCastWithReplies(nil, "0xdoesnotexist")
+	var nerr NeynarError
+	if !errors.As(err, &nerr) || nerr.status != http.StatusNotFound {
+		t.Fatalf("expected 404 NeynarError, got %v", err)
+	}
+}
+
+func TestPostCast(t *testing.T) {
+	c, _ := newTestClient(t)
+	signer := &Signer{FID: 1, UUID: neynartest.SignerUUID}
+
+	resp, err := c.PostCast(signer, "a reply", testCastHash, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Cast.Hash == "" {
+		t.Fatal("expected hash of new cast")
+	}
+
+	cast, err := c.GetCastWithReplies(signer, testCastHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cast.DirectReplies) != 3 {
+		t.Errorf("expected posted reply in conversation, got %d replies", len(cast.DirectReplies))
+	}
+}
+
+func TestPostCastErrors(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	if _, err := c.PostCast(nil, "hi", "", "", 0); err == nil {
+		t.Error("expected error without signer")
+	}
+
+	revoked := &Signer{FID: 2, UUID: neynartest.RevokedSignerUUID}
+	_, err := c.PostCast(revoked, "hi", "", "", 0)
+	var nerr NeynarError
+	if !errors.As(err, &nerr) || nerr.status != http.StatusForbidden {
+		t.Errorf("expected 403 for revoked signer, got %v", err)
+	}
+	if !IsSignerRevoked(err) {
+		t.Error("expected revoked signer error")
+	}
+
+	unknown := &Signer{FID: 2, UUID: "not-a-signer"}
+	if err := c.React(unknown, testCastHash, Like); !IsSignerRevoked(err) {
+		t.Errorf("expected unknown signer to be treated as revoked, got %v", err)
+	}
+
+	limited := NeynarError{message: "This endpoint is not available on your plan", status: http.StatusForbidden, path: "/cast"}
+	if IsSignerRevoked(limited) {
+		t.Error("expected a 403 unrelated to the signer not to be treated as revoked")
+	}
+
+	c.SetAPIKey("wrong")
+	signer := &Signer{FID: 1, UUID: neynartest.SignerUUID}
+	if _, err := c.PostCast(signer, "hi", "", "", 0); err == nil || IsSignerRevoked(err) {
+		t.Errorf("expected bad api key not to be treated as revoked signer, got %v", err)
+	}
+}
+
+func TestReact(t *testing.T) {
+	c, srv := newTestClient(t)
+	signer := &Signer{FID: 1, UUID: neynartest.SignerUUID}
+
+	if err := c.React(signer, testCastHash, Like); err != nil {
+		t.Fatal(err)
+	}
+	if !srv.Liked(testCastHash, 1) {
+		t.Error("expected like to be recorded")
+	}
+
+	cast, err := c.GetCastWithReplies(signer, testCastHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cast.ViewerContext.Liked {
+		t.Error("expected viewer context to reflect like")
+	}
+	if cast.Reactions.LikesCount != 13 {
+		t.Errorf("expected likes count 13, got %d", cast.Reactions.LikesCount)
+	}
+}
@@ -1,15 +1,11 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"time"
-
-	"github.com/treethought/tofui/db"
 )
 
 type ChannelsResponse struct {
@@ -37,15 +33,15 @@ type Channel struct {
 	Hosts         []User `json:"hosts"`
 }
 
-func cacheChannels(channels []*Channel) {
+func (c *Client) cacheChannels(channels []*Channel) {
 	for _, ch := range channels {
 		key := fmt.Sprintf("channel:%s", ch.ParentURL)
 		mkey := fmt.Sprintf("channelurl:%s", ch.ID)
-		_ = db.GetDB().Set([]byte(mkey), []byte(ch.ParentURL))
+		_ = c.store.Set([]byte(mkey), []byte(ch.ParentURL))
 
 		if d, err := json.Marshal(ch); err == nil {
-			if err := db.GetDB().Set([]byte(key), []byte(d)); err != nil {
-				log.Println("failed to cache channel: ", err)
+			if err := c.store.Set([]byte(key), []byte(d)); err != nil {
+				slog.Error("failed to cache channel", "error", err)
 			}
 		}
 	}
@@ -53,7 +49,7 @@ func cacheChannels(channels []*Channel) {
 
 func (c *Client) GetChannelUrlById(id string) string {
 	key := fmt.Sprintf("channelurl:%s", id)
-	cached, err := db.GetDB().Get([]byte(key))
+	cached, err := c.store.Get([]byte(key))
 	if err != nil {
 		return ""
 	}
@@ -70,13 +66,13 @@ func (c *Client) GetUserChannels(fid uint64, active bool, opts ...RequestOption)
 	opts = append(opts, WithFID(fid))
 
 	var resp ChannelsResponse
-	if err := c.doRequestInto(context.TODO(), path, &resp, opts...); err != nil {
+	if err := c.doRequestInto(c.ctx, path, &resp, opts...); err != nil {
 		return nil, err
 	}
 	if resp.Channels == nil {
 		return nil, errors.New("no channels found")
 	}
-	cacheChannels(resp.Channels)
+	c.cacheChannels(resp.Channels)
 
 	return resp.Channels, nil
 }
@@ -86,24 +82,32 @@ func (c *Client) SearchChannel(q string) ([]*Channel, error) {
 	opts := []RequestOption{WithQuery("q", q)}
 
 	var resp ChannelsResponse
-	if err := c.doRequestInto(context.TODO(), path, &resp, opts...); err != nil {
+	if err := c.doRequestInto(c.ctx, path, &resp, opts...); err != nil {
 		return nil, err
 	}
 	if resp.Channels == nil {
 		return nil, errors.New("no channels found")
 	}
-	cacheChannels(resp.Channels)
+	c.cacheChannels(resp.Channels)
 	return resp.Channels, nil
 }
 
 func (c *Client) GetChannelByParentUrl(q string) (*Channel, error) {
 	// TODO: cache once and do mappiing from name to url
 	key := fmt.Sprintf("channel:%s", q)
-	cached, err := db.GetDB().Get([]byte(key))
+	cached, fresh, err := c.store.GetFresh([]byte(key))
 	if err == nil {
 		ch := &Channel{}
 		if err := json.Unmarshal(cached, ch); err != nil {
-			log.Fatal("failed to unmarshal cached channel: ", err)
+			slog.Warn("dropping corrupt cached channel", "error", err)
+			_ = c.store.Delete([]byte(key))
+			return c.fetchChannel(q, "parent_url")
+		}
+		if !fresh {
+			c.revalidate(key, func() error {
+				_, err := c.fetchChannel(q, "parent_url")
+				return err
+			})
 		}
 		return ch, nil
 	}
@@ -124,67 +128,48 @@ func (c *Client) fetchChannel(q, ttype string) (*Channel, error) {
 	opts := []RequestOption{WithQuery("id", q), WithQuery("type", ttype)}
 
 	var resp ChannelResponse
-	err := c.doRequestInto(context.TODO(), path, &resp, opts...)
+	err := c.doRequestInto(c.ctx, path, &resp, opts...)
 	if err != nil {
 		return nil, err
 	}
 	if resp.Channel.Name == "" {
 		return nil, errors.New("channel name empty")
 	}
-	cacheChannels([]*Channel{resp.Channel})
+	c.cacheChannels([]*Channel{resp.Channel})
 	return resp.Channel, nil
 
 }
 
 func (c *Client) FetchAllChannels() error {
-	var resp ChannelsResponse
-	var res *http.Response
-
-	defer db.GetDB().Set([]byte("channelsloaded"), []byte(fmt.Sprintf("%d", time.Now().Unix())))
+	defer c.store.Set([]byte("channelsloaded"), []byte(fmt.Sprintf("%d", time.Now().Unix())))
 
+	var cursor *string
 	for {
-		if res != nil {
-			res.Body.Close()
-		}
-		url := c.buildEndpoint(fmt.Sprintf("/channel/list?limit=50"))
-		if resp.Next.Cursor != nil {
-			url += fmt.Sprintf("&cursor=%s", *resp.Next.Cursor)
-		}
-		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
-		if err != nil {
-			return err
+		opts := []RequestOption{WithLimit(50)}
+		if cursor != nil {
+			opts = append(opts, WithQuery("cursor", *cursor))
 		}
-		req.Header.Add("accept", "application/json")
-		req.Header.Add("api_key", c.apiKey)
-
-		res, err = c.c.Do(req)
-		if err != nil {
-			return err
-		}
-
-		if res.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to get channels: %s", res.Status)
-		}
-
-		if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		var resp ChannelsResponse
+		if err := c.doRequestInto(c.ctx, "/channel/list", &resp, opts...); err != nil {
 			return err
 		}
-		cacheChannels(resp.Channels)
+		c.cacheChannels(resp.Channels)
 
 		if resp.Next.Cursor == nil {
 			break
 		}
+		cursor = resp.Next.Cursor
 	}
-	log.Println("channels loaded")
+	slog.Debug("channels loaded")
 
 	return nil
 }
 
 func (c *Client) GetCachedChannelIds() ([]string, error) {
 	prefix := []byte("channelurl:")
-	keys, err := db.GetDB().GetKeys(prefix)
+	keys, err := c.store.GetKeys(prefix)
 	if err != nil {
-		log.Println("failed to get keys: ", err)
+		slog.Error("failed to get keys", "error", err)
 		return nil, err
 	}
 	ids := make([]string, 0)
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestFetchAllChannelsPaginates(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.PageSize = 2
+
+	if err := c.FetchAllChannels(); err != nil {
+		t.Fatal(err)
+	}
+	if hits := srv.Hits("/channel/list"); hits != 3 {
+		t.Errorf("expected 3 page requests for 5 channels, got %d", hits)
+	}
+
+	ids, err := c.GetCachedChannelIds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	want := []string{"art", "dev", "farcaster", "music", "tofui"}
+	if len(ids) != len(want) {
+		t.Fatalf("expected cached ids %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("expected cached ids %v, got %v", want, ids)
+			break
+		}
+	}
+}
+
+func TestGetChannelCached(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	ch, err := c.GetChannelById("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Name != "dev" || ch.Lead.Username != "alice" {
+		t.Fatalf("unexpected channel: %+v", ch)
+	}
+	if hits := srv.Hits("/channel"); hits != 1 {
+		t.Fatalf("expected 1 request, got %d", hits)
+	}
+
+	if c.GetChannelUrlById("dev") != ch.ParentURL {
+		t.Errorf("expected id to parent url mapping to be cached")
+	}
+	cached, err := c.GetChannelByParentUrl(ch.ParentURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.ID != "dev" {
+		t.Errorf("expected cached channel dev, got %s", cached.ID)
+	}
+	if _, err := c.GetChannelById("dev"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := srv.Hits("/channel"); hits != 1 {
+		t.Errorf("expected cached lookups to skip the api, got %d requests", hits)
+	}
+}
+
+func TestGetChannelNotFound(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	if _, err := c.GetChannelById("does-not-exist"); err == nil {
+		t.Fatal("expected error for unknown channel")
+	}
+}
+
+func TestGetUserChannels(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	channels, err := c.GetUserChannels(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 3 {
+		t.Fatalf("expected 3 active channels, got %d", len(channels))
+	}
+	if c.GetChannelUrlById("tofui") != "https://tofui.xyz" {
+		t.Error("expected user channels to be cached")
+	}
+}
+
+func TestSearchChannel(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	channels, err := c.SearchChannel("mus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 1 || channels[0].ID != "music" {
+		t.Fatalf("expected music channel, got %+v", channels)
+	}
+}
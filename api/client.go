@@ -4,18 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/treethought/tofui/config"
-)
+	"golang.org/x/time/rate"
 
-var (
-	client     *Client
-	clientOnce sync.Once
+	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
+	"github.com/treethought/tofui/metrics"
 )
 
 type NeynarError struct {
@@ -35,24 +38,93 @@ func (e NeynarError) Error() string {
 	return fmt.Sprintf("%s: %s", e.path, e.message)
 }
 
+func (e NeynarError) Unwrap() error {
+	return e.error
+}
+
+// Client is safe to share between sessions. Use WithLimiter to give a
+// session its own request budget.
 type Client struct {
+	*clientState
+	limiter *rate.Limiter
+}
+
+type clientState struct {
 	c              *http.Client
 	apiKey         string
 	baseURL        string
 	clientID       string
 	persistantOpts []RequestOption
+	store          db.Store
+
+	signersMu sync.RWMutex
+	signers   map[string]*Signer
+	active    map[string]uint64
+	signerKey *SignerKey
+
+	// keys of stale cache entries currently being refreshed
+	refreshing sync.Map
+	refreshWG  sync.WaitGroup
+
+	// ctx is cancelled by Close, ending in-flight requests
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewClient(cfg *config.Config, store db.Store) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{clientState: &clientState{
+		ctx:      ctx,
+		cancel:   cancel,
+		c:        http.DefaultClient,
+		apiKey:   cfg.Neynar.APIKey,
+		baseURL:  cfg.Neynar.BaseUrl,
+		clientID: cfg.Neynar.ClientID,
+		store:    store,
+		signers:  make(map[string]*Signer),
+		active:   make(map[string]uint64),
+	}}
+}
+
+// Close cancels in-flight requests and waits for background cache refreshes
+// to finish, so the store can be closed after it.
+func (c *Client) Close() {
+	c.cancel()
+	c.refreshWG.Wait()
+}
+
+// WithLimiter returns a client sharing c's cache and signers whose requests
+// wait on l. A nil l removes the limit.
+func (c *Client) WithLimiter(l *rate.Limiter) *Client {
+	return &Client{clientState: c.clientState, limiter: l}
+}
+
+func (c *Client) wait(ctx context.Context) error {
+	if c.limiter == nil {
+		return nil
+	}
+	return c.limiter.Wait(ctx)
 }
 
-func NewClient(cfg *config.Config) *Client {
-	clientOnce.Do(func() {
-		client = &Client{
-			c:        http.DefaultClient,
-			apiKey:   cfg.Neynar.APIKey,
-			baseURL:  cfg.Neynar.BaseUrl,
-			clientID: cfg.Neynar.ClientID,
+// Store returns the cache used by the client.
+func (c *Client) Store() db.Store {
+	return c.store
+}
+
+// revalidate runs refresh in the background to replace the stale cache entry
+// at key, unless a refresh for it is already in flight.
+func (c *Client) revalidate(key string, refresh func() error) {
+	if _, busy := c.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	c.refreshWG.Add(1)
+	go func() {
+		defer c.refreshWG.Done()
+		defer c.refreshing.Delete(key)
+		if err := refresh(); err != nil {
+			slog.Error("failed to refresh cache entry", "key", key, "error", err)
 		}
-	})
-	return client
+	}()
 }
 
 func (c *Client) SetOptions(opts ...RequestOption) {
@@ -67,13 +139,31 @@ func (c *Client) SetAPIKey(key string) {
 	c.apiKey = key
 }
 
+// ErrInvalidAPIKey is returned by CheckAPIKey when Neynar rejects the key.
+var ErrInvalidAPIKey = errors.New("neynar rejected the api key")
+
+// CheckAPIKey makes a cheap, uncached request to confirm the API key and
+// base URL work.
+func (c *Client) CheckAPIKey() error {
+	var resp BulkUsersResponse
+	err := c.doRequestInto(c.ctx, "/user/bulk", &resp, WithQuery("fids", "1"))
+	var nerr NeynarError
+	if errors.As(err, &nerr) && (nerr.status == http.StatusUnauthorized || nerr.status == http.StatusForbidden) {
+		return ErrInvalidAPIKey
+	}
+	return err
+}
+
 func (c *Client) doPostRequest(ctx context.Context, path string, body io.Reader, opts ...RequestOption) (*http.Response, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
 	url := c.buildEndpoint(path)
 
-	log.Println("sending request to: ", url)
+	slog.Debug("sending request", "method", http.MethodPost, "path", path)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
-		log.Println("failed to create request: ", err)
+		slog.Error("failed to create request", "error", err)
 		return nil, err
 	}
 	req.Header.Add("accept", "application/json")
@@ -81,17 +171,20 @@ func (c *Client) doPostRequest(ctx context.Context, path string, body io.Reader,
 	req.Header.Add("content-type", "application/json")
 
 	for _, opt := range c.persistantOpts {
-		log.Println("applying persistant option")
+		slog.Debug("applying persistant option")
 		opt(req)
 	}
 
 	for _, opt := range opts {
 		opt(req)
 	}
-	return c.c.Do(req)
+	return c.do(req, path)
 }
 
 func (c *Client) doRequest(ctx context.Context, path string, opts ...RequestOption) (*http.Response, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
 	url := c.buildEndpoint(path)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -107,7 +200,20 @@ func (c *Client) doRequest(ctx context.Context, path string, opts ...RequestOpti
 	for _, opt := range opts {
 		opt(req)
 	}
-	return c.c.Do(req)
+	return c.do(req, path)
+}
+
+// do sends req, recording its latency and status under path.
+func (c *Client) do(req *http.Request, path string) (*http.Response, error) {
+	start := time.Now()
+	res, err := c.c.Do(req)
+	metrics.NeynarLatency.WithLabelValues(req.Method, path).Observe(time.Since(start).Seconds())
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(res.StatusCode)
+	}
+	metrics.NeynarRequests.WithLabelValues(req.Method, path, status).Inc()
+	return res, err
 }
 
 func (c *Client) doRequestInto(ctx context.Context, path string, v interface{}, opts ...RequestOption) error {
@@ -133,7 +239,6 @@ func (c *Client) doPostInto(ctx context.Context, path string, body interface{},
 	if err != nil {
 		return NeynarError{"failed to marshal body", 0, path, err}
 	}
-	log.Println("sending payload: ", string(data))
 
 	r := bytes.NewReader(data)
 	resp, err := c.doPostRequest(ctx, path, r, opts...)
@@ -153,6 +258,22 @@ func (c *Client) doPostInto(ctx context.Context, path string, body interface{},
 	return nil
 }
 
+// IsSignerRevoked reports whether err means the signer used for a request
+// can no longer act for its account, because it was revoked, is not
+// approved or does not exist.
+func IsSignerRevoked(err error) bool {
+	var nerr NeynarError
+	if !errors.As(err, &nerr) {
+		return false
+	}
+	switch nerr.status {
+	case http.StatusForbidden, http.StatusUnauthorized, http.StatusNotFound:
+		// also returned for a bad api key, a plan limit or a missing cast
+		return strings.Contains(strings.ToLower(nerr.message), "signer")
+	}
+	return false
+}
+
 type RequestOption func(*http.Request)
 
 func setQueryParam(r *http.Request, key, value string) {
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/time/rate"
+
+	"github.com/treethought/tofui/api/neynartest"
+	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
+	"github.com/treethought/tofui/metrics"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func newTestClient(t *testing.T) (*Client, *neynartest.Server) {
+	t.Helper()
+	srv := neynartest.NewServer()
+	t.Cleanup(srv.Close)
+	cfg := &config.Config{}
+	cfg.Neynar.APIKey = neynartest.APIKey
+	cfg.Neynar.BaseUrl = srv.URL
+	c := NewClient(cfg, db.NewMemoryStore(cfg))
+	c.c = srv.Client()
+	return c, srv
+}
+
+func cfgFor(c *Client) *config.Config {
+	cfg := &config.Config{}
+	cfg.Neynar.APIKey = c.apiKey
+	cfg.Neynar.BaseUrl = c.baseURL
+	return cfg
+}
+
+func TestUnauthorized(t *testing.T) {
+	c, _ := newTestClient(t)
+	c.SetAPIKey("wrong")
+
+	_, err := c.GetFeed(&FeedRequest{FeedType: "following", FID: 1})
+	var nerr NeynarError
+	if !errors.As(err, &nerr) {
+		t.Fatalf("expected NeynarError, got %v", err)
+	}
+	if nerr.status != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", nerr.status)
+	}
+	if nerr.path != "/feed" {
+		t.Errorf("expected path /feed, got %s", nerr.path)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.Fail("/feed", http.StatusInternalServerError, `{"message":"boom"}`)
+
+	_, err := c.GetFeed(&FeedRequest{FeedType: "following", FID: 1})
+	var nerr NeynarError
+	if !errors.As(err, &nerr) {
+		t.Fatalf("expected NeynarError, got %v", err)
+	}
+	if nerr.status != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", nerr.status)
+	}
+	if nerr.message != `{"message":"boom"}` {
+		t.Errorf("expected response body as message, got %q", nerr.message)
+	}
+
+	srv.ClearFailures()
+	if _, err := c.GetFeed(&FeedRequest{FeedType: "following", FID: 1}); err != nil {
+		t.Errorf("expected recovery after failures cleared: %v", err)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	c, srv := newTestClient(t)
+	srv.Fail("/notifications", http.StatusOK, "not json")
+
+	_, err := c.GetNotifications(1)
+	var nerr NeynarError
+	if !errors.As(err, &nerr) {
+		t.Fatalf("expected NeynarError, got %v", err)
+	}
+	if nerr.error == nil {
+		t.Error("expected wrapped decode error")
+	}
+}
+
+func TestRequestOptions(t *testing.T) {
+	c, srv := newTestClient(t)
+	c.SetOptions(WithQuery("viewer_fid", "1"))
+
+	resp, err := c.GetFeed(&FeedRequest{FeedType: "filter", FilterType: "fids", FIDs: []uint64{2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Casts) != 1 {
+		t.Fatalf("expected 1 cast by fid 2, got %d", len(resp.Casts))
+	}
+	if !resp.Casts[0].Author.ViewerContext.Following {
+		t.Error("expected persistent viewer_fid option to be applied")
+	}
+	if srv.Hits("/feed") != 1 {
+		t.Errorf("expected 1 request, got %d", srv.Hits("/feed"))
+	}
+}
+
+func TestWithLimiter(t *testing.T) {
+	c, srv := newTestClient(t)
+	limited := c.WithLimiter(rate.NewLimiter(rate.Every(time.Hour), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := &FeedRequest{FeedType: "filter", FilterType: "fids", FIDs: []uint64{2}}
+	var resp FeedResponse
+	if err := limited.doRequestInto(ctx, "/feed", &resp, req.opts()...); err != nil {
+		t.Fatal(err)
+	}
+	if err := limited.doRequestInto(ctx, "/feed", &resp, req.opts()...); err == nil {
+		t.Error("expected second request to exceed the limit")
+	}
+	if srv.Hits("/feed") != 1 {
+		t.Errorf("expected 1 request, got %d", srv.Hits("/feed"))
+	}
+
+	// the shared client is not limited
+	if _, err := c.GetFeed(req); err != nil {
+		t.Error("expected unlimited client to be unaffected: ", err)
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	c, _ := newTestClient(t)
+	ok := metrics.NeynarRequests.WithLabelValues(http.MethodGet, "/feed", "200")
+	failed := metrics.NeynarRequests.WithLabelValues(http.MethodGet, "/feed", "500")
+	before, beforeFailed := testutil.ToFloat64(ok), testutil.ToFloat64(failed)
+
+	req := &FeedRequest{FeedType: "filter", FilterType: "fids", FIDs: []uint64{2}}
+	if _, err := c.GetFeed(req); err != nil {
+		t.Fatal(err)
+	}
+	c2, srv := newTestClient(t)
+	srv.Fail("/feed", http.StatusInternalServerError, "boom")
+	c2.GetFeed(req)
+
+	if got := testutil.ToFloat64(ok) - before; got != 1 {
+		t.Errorf("expected 1 successful request counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(failed) - beforeFailed; got != 1 {
+		t.Errorf("expected 1 failed request counted, got %v", got)
+	}
+}
+
+func TestClose(t *testing.T) {
+	c, srv := newTestClient(t)
+	c.Close()
+
+	_, err := c.GetFeed(&FeedRequest{FeedType: "filter", FilterType: "fids", FIDs: []uint64{2}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected requests after Close to be cancelled, got %v", err)
+	}
+	if srv.Hits("/feed") != 0 {
+		t.Errorf("expected no requests, got %d", srv.Hits("/feed"))
+	}
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	c, srv := newTestClient(t)
+	if err := c.CheckAPIKey(); err != nil {
+		t.Fatal(err)
+	}
+	c.SetAPIKey("wrong")
+	if err := c.CheckAPIKey(); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected ErrInvalidAPIKey, got %v", err)
+	}
+	if _, err := c.store.Get([]byte("user:1")); err == nil {
+		t.Error("expected the check not to be cached")
+	}
+	if srv.Hits("/user/bulk") != 2 {
+		t.Errorf("expected 2 requests, got %d", srv.Hits("/user/bulk"))
+	}
+}
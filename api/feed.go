@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"fmt"
 )
 
@@ -50,13 +49,16 @@ func (r *FeedRequest) opts() []RequestOption {
 
 type FeedResponse struct {
 	Casts []*Cast
+	Next  struct {
+		Cursor *string `json:"cursor"`
+	} `json:"next"`
 }
 
 func (c *Client) GetFeed(r *FeedRequest) (*FeedResponse, error) {
 	path := "/feed"
 	opts := r.opts()
 	var resp FeedResponse
-	if err := c.doRequestInto(context.TODO(), path, &resp, opts...); err != nil {
+	if err := c.doRequestInto(c.ctx, path, &resp, opts...); err != nil {
 		return nil, err
 	}
 	return &resp, nil
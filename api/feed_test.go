@@ -0,0 +1,73 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestGetFeedFollowing(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	resp, err := c.GetFeed(&FeedRequest{FeedType: "following", FID: 1, ViewerFID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Casts) != 3 {
+		t.Fatalf("expected 3 casts from followed users, got %d", len(resp.Casts))
+	}
+	for _, cast := range resp.Casts {
+		if cast.ParentHash != "" {
+			t.Errorf("expected only top level casts, got reply %s", cast.Hash)
+		}
+		if cast.Author.Username == "" {
+			t.Errorf("expected hydrated author for cast %s", cast.Hash)
+		}
+	}
+	if resp.Next.Cursor != nil {
+		t.Errorf("expected no further pages, got cursor %s", *resp.Next.Cursor)
+	}
+}
+
+func TestGetFeedPagination(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	req := &FeedRequest{FeedType: "filter", FilterType: "global_trending", Limit: 2}
+	seen := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		resp, err := c.GetFeed(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Casts) > 2 {
+			t.Fatalf("expected at most 2 casts per page, got %d", len(resp.Casts))
+		}
+		for _, cast := range resp.Casts {
+			if seen[cast.Hash] {
+				t.Fatalf("cast %s returned twice", cast.Hash)
+			}
+			seen[cast.Hash] = true
+		}
+		if resp.Next.Cursor == nil {
+			break
+		}
+		req.Cursor = *resp.Next.Cursor
+	}
+	if len(seen) != 4 {
+		t.Errorf("expected 4 top level casts across pages, got %d", len(seen))
+	}
+	if srv.Hits("/feed") != 2 {
+		t.Errorf("expected 2 page requests, got %d", srv.Hits("/feed"))
+	}
+}
+
+func TestGetFeedChannel(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	pu := "https://warpcast.com/~/channel/music"
+	resp, err := c.GetFeed(&FeedRequest{FeedType: "filter", FilterType: "parent_url", ParentURL: pu})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Casts) != 1 || resp.Casts[0].ParentURL != pu {
+		t.Fatalf("expected the single music cast, got %+v", resp.Casts)
+	}
+}
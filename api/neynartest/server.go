@@ -0,0 +1,670 @@
+// Package neynartest provides an offline stand-in for the Neynar v2 farcaster
+// API. It serves the endpoints tofui uses from the JSON fixtures in this
+// package so the api and ui packages can be exercised without network access.
+package neynartest
+
+import (
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// APIKey is the api key the server accepts unless Server.APIKey is changed.
+const APIKey = "neynar-test-key"
+
+// SignerUUID is the approved fixture signer for the user with fid 1.
+const SignerUUID = "00000000-0000-4000-8000-000000000001"
+
+// RevokedSignerUUID is a fixture signer that has been revoked.
+const RevokedSignerUUID = "00000000-0000-4000-8000-000000000002"
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+type object = map[string]interface{}
+
+type failure struct {
+	status int
+	body   string
+}
+
+// Server is an httptest server implementing the subset of the Neynar API
+// used by tofui. Its URL can be used directly as the neynar base_url.
+type Server struct {
+	*httptest.Server
+
+	// APIKey is the value required in the api_key header.
+	APIKey string
+	// PageSize caps the number of items returned per page by paginated
+	// endpoints, regardless of the requested limit. Zero means no cap.
+	PageSize int
+
+	mu            sync.Mutex
+	users         map[uint64]object
+	casts         []object
+	channels      []object
+	userChannels  map[uint64][]string
+	follows       map[uint64][]uint64
+	notifications map[uint64][]object
+	signers       map[string]object
+	likes         map[string]map[uint64]bool
+	recasts       map[string]map[uint64]bool
+	hits          map[string]int
+	failures      map[string]failure
+	nextHash      int
+}
+
+// NewServer starts a server loaded with the default fixtures. Callers should
+// Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		APIKey:   APIKey,
+		likes:    make(map[string]map[uint64]bool),
+		recasts:  make(map[string]map[uint64]bool),
+		hits:     make(map[string]int),
+		failures: make(map[string]failure),
+	}
+	s.loadFixtures()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", s.handleFeed)
+	mux.HandleFunc("/cast", s.handlePostCast)
+	mux.HandleFunc("/cast/conversation", s.handleConversation)
+	mux.HandleFunc("/reaction", s.handleReaction)
+	mux.HandleFunc("/user/bulk", s.handleBulkUsers)
+	mux.HandleFunc("/user/channels", s.handleUserChannels)
+	mux.HandleFunc("/channel", s.handleChannel)
+	mux.HandleFunc("/channel/list", s.handleChannelList)
+	mux.HandleFunc("/channel/search", s.handleChannelSearch)
+	mux.HandleFunc("/channel/user", s.handleUserChannels)
+	mux.HandleFunc("/notifications", s.handleNotifications)
+	mux.HandleFunc("/signer", s.handleSigner)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+func mustLoad(name string, v interface{}) {
+	d, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal(d, v); err != nil {
+		panic(fmt.Sprintf("neynartest: invalid fixture %s: %s", name, err))
+	}
+}
+
+func (s *Server) loadFixtures() {
+	var users []object
+	mustLoad("users.json", &users)
+	s.users = make(map[uint64]object)
+	for _, u := range users {
+		s.users[fidOf(u["fid"])] = u
+	}
+
+	mustLoad("casts.json", &s.casts)
+	mustLoad("channels.json", &s.channels)
+
+	var uc map[string][]string
+	mustLoad("user_channels.json", &uc)
+	s.userChannels = make(map[uint64][]string)
+	for k, v := range uc {
+		s.userChannels[parseFID(k)] = v
+	}
+
+	var follows map[string][]uint64
+	mustLoad("follows.json", &follows)
+	s.follows = make(map[uint64][]uint64)
+	for k, v := range follows {
+		s.follows[parseFID(k)] = v
+	}
+
+	var notifs map[string][]object
+	mustLoad("notifications.json", &notifs)
+	s.notifications = make(map[uint64][]object)
+	for k, v := range notifs {
+		s.notifications[parseFID(k)] = v
+	}
+
+	var signers []object
+	mustLoad("signers.json", &signers)
+	s.signers = make(map[string]object)
+	for _, sg := range signers {
+		s.signers[sg["signer_uuid"].(string)] = sg
+	}
+}
+
+// Reset restores the fixtures and clears recorded reactions, posted casts,
+// request counts and failures.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadFixtures()
+	s.likes = make(map[string]map[uint64]bool)
+	s.recasts = make(map[string]map[uint64]bool)
+	s.hits = make(map[string]int)
+	s.failures = make(map[string]failure)
+	s.nextHash = 0
+}
+
+// Hits returns the number of requests received for path.
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// Fail makes every subsequent request to path respond with status and body
+// until ClearFailures is called. The body is sent as is, so it may be
+// invalid JSON.
+func (s *Server) Fail(path string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = failure{status, body}
+}
+
+// ClearFailures removes all failures registered with Fail.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = make(map[string]failure)
+}
+
+// Liked reports whether fid has liked the cast with the given hash.
+func (s *Server) Liked(hash string, fid uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.likes[hash][fid]
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		f, failing := s.failures[r.URL.Path]
+		s.mu.Unlock()
+
+		if r.Header.Get("api_key") != s.APIKey {
+			writeError(w, http.StatusUnauthorized, "Unauthorized", "Invalid api key")
+			return
+		}
+		if failing {
+			w.WriteHeader(f.status)
+			w.Write([]byte(f.body))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(object{"code": code, "message": msg})
+}
+
+func fidOf(v interface{}) uint64 {
+	switch n := v.(type) {
+	case float64:
+		return uint64(n)
+	case uint64:
+		return n
+	case int:
+		return uint64(n)
+	}
+	return 0
+}
+
+func parseFID(s string) uint64 {
+	fid, _ := strconv.ParseUint(s, 10, 64)
+	return fid
+}
+
+func contains(fids []uint64, fid uint64) bool {
+	for _, f := range fids {
+		if f == fid {
+			return true
+		}
+	}
+	return false
+}
+
+// copyObject deep copies a fixture so responses can be decorated without
+// mutating server state.
+func copyObject(o object) object {
+	d, _ := json.Marshal(o)
+	var c object
+	json.Unmarshal(d, &c)
+	return c
+}
+
+// page returns the slice of items selected by the request's limit and cursor
+// along with the cursor for the following page, if any.
+func (s *Server) page(r *http.Request, items []object, def int) ([]object, interface{}) {
+	limit := def
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if s.PageSize > 0 && limit > s.PageSize {
+		limit = s.PageSize
+	}
+	offset := 0
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		if d, err := base64.RawURLEncoding.DecodeString(c); err == nil {
+			offset, _ = strconv.Atoi(string(d))
+		}
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := offset + limit
+	if end >= len(items) {
+		return items[offset:], nil
+	}
+	return items[offset:end], base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+}
+
+func (s *Server) user(fid, viewer uint64) object {
+	u, ok := s.users[fid]
+	if !ok {
+		return object{"object": "user", "fid": fid}
+	}
+	u = copyObject(u)
+	if viewer != 0 {
+		u["viewer_context"] = object{
+			"following":   contains(s.follows[viewer], fid),
+			"followed_by": contains(s.follows[fid], viewer),
+		}
+	}
+	return u
+}
+
+func (s *Server) findCast(hash string) object {
+	for _, c := range s.casts {
+		if c["hash"] == hash {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *Server) hydrateCast(c object, viewer uint64, depth int) object {
+	hash, _ := c["hash"].(string)
+	c = copyObject(c)
+	author, _ := c["author"].(object)
+	c["author"] = s.user(fidOf(author["fid"]), viewer)
+	c["viewer_context"] = object{
+		"liked":    s.likes[hash][viewer],
+		"recasted": s.recasts[hash][viewer],
+	}
+	if depth > 0 {
+		replies := []object{}
+		for _, r := range s.casts {
+			if r["parent_hash"] == hash {
+				replies = append(replies, s.hydrateCast(r, viewer, depth-1))
+			}
+		}
+		c["direct_replies"] = replies
+	}
+	return c
+}
+
+func (s *Server) hydrateChannel(ch object) object {
+	ch = copyObject(ch)
+	lead, _ := ch["lead"].(object)
+	ch["lead"] = s.user(fidOf(lead["fid"]), 0)
+	return ch
+}
+
+func (s *Server) findChannel(q string) object {
+	for _, ch := range s.channels {
+		if ch["id"] == q || ch["parent_url"] == q || ch["name"] == q {
+			return ch
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	viewer := parseFID(q.Get("viewer_fid"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var match func(c object) bool
+	switch {
+	case q.Get("feed_type") == "following":
+		following := s.follows[parseFID(q.Get("fid"))]
+		match = func(c object) bool {
+			author, _ := c["author"].(object)
+			return c["parent_hash"] == nil && contains(following, fidOf(author["fid"]))
+		}
+	case q.Get("filter_type") == "parent_url":
+		match = func(c object) bool { return c["parent_url"] == q.Get("parent_url") }
+	case q.Get("filter_type") == "fids":
+		fids := []uint64{}
+		for _, f := range q["fids"] {
+			for _, p := range strings.Split(f, ",") {
+				fids = append(fids, parseFID(p))
+			}
+		}
+		match = func(c object) bool {
+			author, _ := c["author"].(object)
+			return contains(fids, fidOf(author["fid"]))
+		}
+	default:
+		match = func(c object) bool { return c["parent_hash"] == nil }
+	}
+
+	casts := []object{}
+	for _, c := range s.casts {
+		if match(c) {
+			casts = append(casts, s.hydrateCast(c, viewer, 0))
+		}
+	}
+	sort.SliceStable(casts, func(i, j int) bool {
+		return casts[i]["timestamp"].(string) > casts[j]["timestamp"].(string)
+	})
+	items, next := s.page(r, casts, 25)
+	writeJSON(w, object{"casts": items, "next": object{"cursor": next}})
+}
+
+func (s *Server) handleConversation(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	depth, err := strconv.Atoi(q.Get("reply_depth"))
+	if err != nil {
+		depth = 2
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.findCast(q.Get("identifier"))
+	if c == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "Cast not found")
+		return
+	}
+	cast := s.hydrateCast(c, parseFID(q.Get("viewer_fid")), depth)
+	writeJSON(w, object{"conversation": object{"cast": cast}})
+}
+
+// approvedSigner returns the fid of the signer if it exists and is approved,
+// writing an error response otherwise.
+func (s *Server) approvedSigner(w http.ResponseWriter, uuid string) (uint64, bool) {
+	sg, ok := s.signers[uuid]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "Signer not found")
+		return 0, false
+	}
+	if sg["status"] != "approved" {
+		writeError(w, http.StatusForbidden, "Forbidden", "Signer is not approved")
+		return 0, false
+	}
+	return fidOf(sg["fid"]), true
+}
+
+func (s *Server) handlePostCast(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST required")
+		return
+	}
+	var payload struct {
+		SignerUUID      string `json:"signer_uuid"`
+		Text            string `json:"text"`
+		Parent          string `json:"parent"`
+		ChannelID       string `json:"channel_id"`
+		ParentAuthorFID uint64 `json:"parent_author_fid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidField", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fid, ok := s.approvedSigner(w, payload.SignerUUID)
+	if !ok {
+		return
+	}
+	if payload.Text == "" {
+		writeError(w, http.StatusBadRequest, "InvalidField", "text is required")
+		return
+	}
+
+	s.nextHash++
+	hash := fmt.Sprintf("0x%040x", 0xf000+s.nextHash)
+	cast := object{
+		"object":        "cast",
+		"hash":          hash,
+		"thread_hash":   hash,
+		"parent_hash":   nil,
+		"parent_url":    nil,
+		"parent_author": object{"fid": nil},
+		"author":        object{"fid": fid},
+		"text":          payload.Text,
+		"timestamp":     "2024-06-02T00:00:00.000Z",
+		"embeds":        []object{},
+		"reactions":     object{"likes_count": 0, "recasts_count": 0, "likes": []object{}, "recasts": []object{}},
+		"replies":       object{"count": 0},
+	}
+	if payload.Parent != "" {
+		if parent := s.findCast(payload.Parent); parent != nil {
+			cast["parent_hash"] = payload.Parent
+			cast["thread_hash"] = parent["thread_hash"]
+			cast["parent_author"] = object{"fid": payload.ParentAuthorFID}
+			replies, _ := parent["replies"].(object)
+			replies["count"] = fidOf(replies["count"]) + 1
+		}
+	}
+	if payload.ChannelID != "" {
+		if ch := s.findChannel(payload.ChannelID); ch != nil {
+			cast["parent_url"] = ch["parent_url"]
+		}
+	}
+	s.casts = append(s.casts, cast)
+
+	writeJSON(w, object{"success": true, "cast": s.hydrateCast(cast, 0, 0)})
+}
+
+func (s *Server) handleReaction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "POST required")
+		return
+	}
+	var payload struct {
+		SignerUUID   string `json:"signer_uuid"`
+		ReactionType string `json:"reaction_type"`
+		Target       string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidField", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fid, ok := s.approvedSigner(w, payload.SignerUUID)
+	if !ok {
+		return
+	}
+	cast := s.findCast(payload.Target)
+	if cast == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "Cast not found")
+		return
+	}
+
+	var reactions map[string]map[uint64]bool
+	var countKey string
+	switch payload.ReactionType {
+	case "like":
+		reactions, countKey = s.likes, "likes_count"
+	case "recast":
+		reactions, countKey = s.recasts, "recasts_count"
+	default:
+		writeError(w, http.StatusBadRequest, "InvalidField", "unknown reaction_type")
+		return
+	}
+	if reactions[payload.Target] == nil {
+		reactions[payload.Target] = make(map[uint64]bool)
+	}
+	if !reactions[payload.Target][fid] {
+		reactions[payload.Target][fid] = true
+		counts, _ := cast["reactions"].(object)
+		counts[countKey] = fidOf(counts[countKey]) + 1
+	}
+	writeJSON(w, object{"success": true, "message": "Reaction published"})
+}
+
+func (s *Server) handleSigner(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sg, ok := s.signers[r.URL.Query().Get("signer_uuid")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "Signer not found")
+		return
+	}
+	writeJSON(w, sg)
+}
+
+func (s *Server) handleBulkUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	viewer := parseFID(q.Get("viewer_fid"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []object{}
+	for _, f := range q["fids"] {
+		for _, p := range strings.Split(f, ",") {
+			fid := parseFID(p)
+			if _, ok := s.users[fid]; ok {
+				users = append(users, s.user(fid, viewer))
+			}
+		}
+	}
+	writeJSON(w, object{"users": users})
+}
+
+func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ch object
+	for _, c := range s.channels {
+		if q.Get("type") == "parent_url" && c["parent_url"] == q.Get("id") {
+			ch = c
+		}
+		if q.Get("type") != "parent_url" && c["id"] == q.Get("id") {
+			ch = c
+		}
+	}
+	if ch == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "Channel not found")
+		return
+	}
+	writeJSON(w, object{
+		"channel":        s.hydrateChannel(ch),
+		"viewer_context": object{"following": false},
+	})
+}
+
+func (s *Server) writeChannels(w http.ResponseWriter, r *http.Request, channels []object) {
+	hydrated := make([]object, 0, len(channels))
+	for _, ch := range channels {
+		hydrated = append(hydrated, s.hydrateChannel(ch))
+	}
+	items, next := s.page(r, hydrated, 20)
+	writeJSON(w, object{"channels": items, "next": object{"cursor": next}})
+}
+
+func (s *Server) handleChannelList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writeChannels(w, r, s.channels)
+}
+
+func (s *Server) handleChannelSearch(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(r.URL.Query().Get("q"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := []object{}
+	for _, ch := range s.channels {
+		id, _ := ch["id"].(string)
+		name, _ := ch["name"].(string)
+		if strings.Contains(id, q) || strings.Contains(strings.ToLower(name), q) {
+			channels = append(channels, ch)
+		}
+	}
+	s.writeChannels(w, r, channels)
+}
+
+func (s *Server) handleUserChannels(w http.ResponseWriter, r *http.Request) {
+	fid := parseFID(r.URL.Query().Get("fid"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channels := []object{}
+	for _, id := range s.userChannels[fid] {
+		if ch := s.findChannel(id); ch != nil {
+			channels = append(channels, ch)
+		}
+	}
+	s.writeChannels(w, r, channels)
+}
+
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	fid := parseFID(r.URL.Query().Get("fid"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notifs := []object{}
+	for _, n := range s.notifications[fid] {
+		n = copyObject(n)
+		if hash, ok := n["cast"].(string); ok {
+			if c := s.findCast(hash); c != nil {
+				n["cast"] = s.hydrateCast(c, fid, 0)
+			} else {
+				delete(n, "cast")
+			}
+		}
+		if follows, ok := n["follows"].([]interface{}); ok {
+			for _, f := range follows {
+				f := f.(object)
+				u, _ := f["user"].(object)
+				f["user"] = s.user(fidOf(u["fid"]), fid)
+			}
+		}
+		if reactions, ok := n["reactions"].([]interface{}); ok {
+			for _, rc := range reactions {
+				rc := rc.(object)
+				u, _ := rc["user"].(object)
+				rc["user"] = s.user(fidOf(u["fid"]), fid)
+			}
+		}
+		notifs = append(notifs, n)
+	}
+	items, next := s.page(r, notifs, 25)
+	writeJSON(w, object{"notifications": items, "next": object{"cursor": next}})
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"fmt"
 	"time"
 )
@@ -31,7 +30,7 @@ type Notification struct {
 	Object              string                 `json:"object"`
 	MostRecentTimestamp time.Time              `json:"most_recent_timestamp"`
 	Type                NotificationsType      `json:"type"`
-	Cast                *Cast                   `json:"cast"`
+	Cast                *Cast                  `json:"cast"`
 	Follows             []FollowNotification   `json:"follows"`
 	Reactions           []ReactionNotification `json:"reactions"`
 }
@@ -57,7 +56,7 @@ func (c *Client) GetNotifications(fid uint64, opts ...RequestOption) (*Notificat
 	opts = append(opts, WithFID(fid))
 
 	var resp NotificationsResponse
-	if err := c.doRequestInto(context.TODO(), path, &resp, opts...); err != nil {
+	if err := c.doRequestInto(c.ctx, path, &resp, opts...); err != nil {
 		return nil, err
 	}
 	return &resp, nil
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestGetNotifications(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	resp, err := c.GetNotifications(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Notifications) != 3 {
+		t.Fatalf("expected 3 notifications, got %d", len(resp.Notifications))
+	}
+	follows := resp.Notifications[0]
+	if follows.Type != NotificationsTypeFollows || len(follows.Follows) != 2 {
+		t.Fatalf("unexpected follows notification: %+v", follows)
+	}
+	if follows.Follows[0].User.Username != "alice" {
+		t.Errorf("expected hydrated follower, got %+v", follows.Follows[0].User)
+	}
+	likes := resp.Notifications[1]
+	if likes.Cast == nil || likes.Reactions[0].Cast.Object != "cast_dehydrated" {
+		t.Errorf("unexpected likes notification: %+v", likes)
+	}
+}
+
+func TestGetNotificationsPagination(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	first, err := c.GetNotifications(1, WithLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Notifications) != 2 || first.Next.Cursor == nil {
+		t.Fatalf("expected first page of 2 with cursor, got %d", len(first.Notifications))
+	}
+	second, err := c.GetNotifications(1, WithLimit(2), WithQuery("cursor", *first.Next.Cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Notifications) != 1 || second.Next.Cursor != nil {
+		t.Fatalf("expected final page of 1, got %d", len(second.Notifications))
+	}
+	if second.Notifications[0].Type != NotificationsTypeMention {
+		t.Errorf("expected mention on last page, got %s", second.Notifications[0].Type)
+	}
+	if srv.Hits("/notifications") != 2 {
+		t.Errorf("expected 2 requests, got %d", srv.Hits("/notifications"))
+	}
+}
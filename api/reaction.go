@@ -1,9 +1,8 @@
 package api
 
 import (
-	"context"
 	"errors"
-	"log"
+	"log/slog"
 )
 
 type ReactionType string
@@ -35,13 +34,13 @@ func (c *Client) React(s *Signer, cast string, t ReactionType) error {
 		Target:       cast,
 	}
 
-	log.Println("reacting to cast: ", cast, " with type: ", t)
+	slog.Debug("reacting to cast", "cast", cast, "type", t)
 	var resp ReactionResponse
-	if err := c.doPostInto(context.TODO(), "/reaction", payload, &resp); err != nil {
-		log.Println("failed to react: ", err)
+	if err := c.doPostInto(c.ctx, "/reaction", payload, &resp); err != nil {
+		slog.Error("failed to react", "error", err)
 		return err
 	}
-	log.Println("got reaction response: ", resp)
+	slog.Debug("reacted to cast", "cast", cast, "success", resp.Success)
 
 	if !resp.Success {
 		return errors.New(resp.Message)
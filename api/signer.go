@@ -1,23 +1,13 @@
 package api
 
 import (
-	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"sync"
-
-	"github.com/dgraph-io/badger/v4"
-
-	"github.com/treethought/tofui/db"
-)
-
-var (
-	once sync.Once
-	sdb  *badger.DB
-
-	cache = make(map[string]*Signer)
-	mu    sync.RWMutex
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type Signer struct {
@@ -28,36 +18,284 @@ type Signer struct {
 	PublicKey   string
 }
 
-func SetSigner(s *Signer) {
-	once.Do(func() {
-		d, _ := json.Marshal(s)
-		key := fmt.Sprintf("signer:%s", s.PublicKey)
-		if err := db.GetDB().Set([]byte(key), d); err != nil {
-			log.Fatal("failed to save signer: ", err)
+// SignerStatus is Neynar's record of a signer.
+type SignerStatus struct {
+	UUID      string `json:"signer_uuid"`
+	PublicKey string `json:"public_key"`
+	Status    string `json:"status"`
+	FID       uint64 `json:"fid"`
+}
+
+// LookupSigner fetches the status and fid of a signer from Neynar.
+func (c *Client) LookupSigner(uuid string) (*SignerStatus, error) {
+	var resp SignerStatus
+	if err := c.doRequestInto(c.ctx, "/signer", &resp, WithQuery("signer_uuid", uuid)); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// sealedSigner is how signers are stored, the signer's JSON encrypted with
+// the client's SignerKey.
+type sealedSigner struct {
+	Sealed []byte `json:"sealed"`
+}
+
+var (
+	ErrSignersLocked  = errors.New("signer storage is locked")
+	ErrSignerNotFound = errors.New("account not found")
+)
+
+// Each public key can have several accounts signed in, stored under
+// signer:<pk>:<fid>, with the fid of the one in use at signer:<pk>:active.
+func accountKey(pk string, fid uint64) string {
+	return fmt.Sprintf("signer:%s:%d", pk, fid)
+}
+
+func activeAccountKey(pk string) string {
+	return fmt.Sprintf("signer:%s:active", pk)
+}
+
+// LocalPublicKey is the public key the local user's signers are stored
+// under, SSH users' are stored under a hash of their key.
+const LocalPublicKey = "local"
+
+// SignerScope is whose signers a SignerKey encrypts. The local user's
+// passphrase and the server's key file are different keys, so each only
+// migrates the signers it will later be used to read.
+type SignerScope int
+
+const (
+	LocalSigners SignerScope = iota
+	SSHSigners
+	// AllSigners is for a key file shared by local and SSH mode
+	AllSigners
+)
+
+func (s SignerScope) owns(pk string) bool {
+	switch s {
+	case LocalSigners:
+		return pk == LocalPublicKey
+	case SSHSigners:
+		return pk != LocalPublicKey
+	}
+	return true
+}
+
+// SetSignerKey unlocks signer storage. Signers in scope saved by older
+// versions, in plaintext or one per public key, are migrated.
+func (c *Client) SetSignerKey(k *SignerKey, scope SignerScope) {
+	c.signersMu.Lock()
+	c.signerKey = k
+	c.signersMu.Unlock()
+
+	keys, err := c.store.GetKeys([]byte("signer:"))
+	if err != nil {
+		slog.Error("failed to list signers", "error", err)
+		return
+	}
+	for _, key := range keys {
+		pk, rest, found := strings.Cut(strings.TrimPrefix(string(key), "signer:"), ":")
+		if !scope.owns(pk) {
+			continue
 		}
+		legacy := !found
+		d, err := c.store.Get(key)
+		if err != nil || rest == "active" {
+			continue
+		}
+		var sealed sealedSigner
+		if err := json.Unmarshal(d, &sealed); err != nil {
+			continue
+		}
+		if len(sealed.Sealed) > 0 && !legacy {
+			continue
+		}
+		signer := &Signer{}
+		if len(sealed.Sealed) > 0 {
+			d, err = k.open(sealed.Sealed)
+			if err != nil {
+				slog.Error("failed to decrypt signer", "error", err)
+				continue
+			}
+		}
+		if err := json.Unmarshal(d, signer); err != nil || signer.FID == 0 {
+			continue
+		}
+		slog.Info("migrating signer", "key", string(key))
+		if err := c.saveSigner(signer); err != nil {
+			slog.Error("failed to migrate signer", "error", err)
+			continue
+		}
+		if legacy {
+			if c.GetSigner(signer.PublicKey) == nil {
+				if _, err := c.UseSigner(signer.PublicKey, signer.FID); err != nil {
+					slog.Error("failed to activate signer", "error", err)
+				}
+			}
+			_ = c.store.Delete(key)
+		}
+	}
+}
+
+// SetSigner saves the account and makes it the active one for its public
+// key.
+func (c *Client) SetSigner(s *Signer) error {
+	if err := c.saveSigner(s); err != nil {
+		return err
+	}
+	_, err := c.UseSigner(s.PublicKey, s.FID)
+	return err
+}
+
+func (c *Client) saveSigner(s *Signer) error {
+	c.signersMu.RLock()
+	k := c.signerKey
+	c.signersMu.RUnlock()
+	if k == nil {
+		return ErrSignersLocked
+	}
+	d, _ := json.Marshal(s)
+	d, _ = json.Marshal(sealedSigner{Sealed: k.seal(d)})
+	key := accountKey(s.PublicKey, s.FID)
+	if err := c.store.Set([]byte(key), d); err != nil {
+		return fmt.Errorf("failed to save signer: %w", err)
+	}
+	c.signersMu.Lock()
+	c.signers[key] = s
+	c.signersMu.Unlock()
+	return nil
+}
+
+// GetSigner returns the active account for the public key, or nil if none
+// is signed in.
+func (c *Client) GetSigner(pk string) *Signer {
+	c.signersMu.RLock()
+	fid, ok := c.active[pk]
+	c.signersMu.RUnlock()
+	if !ok {
+		d, err := c.store.Get([]byte(activeAccountKey(pk)))
+		if err != nil {
+			slog.Debug("no signer found in db")
+			return nil
+		}
+		fid, err = strconv.ParseUint(string(d), 10, 64)
+		if err != nil {
+			slog.Error("invalid active account", "error", err)
+			return nil
+		}
+		c.signersMu.Lock()
+		c.active[pk] = fid
+		c.signersMu.Unlock()
+	}
+	return c.loadSigner(accountKey(pk, fid))
+}
+
+// Signers returns all accounts signed in with the public key.
+func (c *Client) Signers(pk string) []*Signer {
+	prefix := fmt.Sprintf("signer:%s:", pk)
+	keys, err := c.store.GetKeys([]byte(prefix))
+	if err != nil {
+		slog.Error("failed to list signers", "error", err)
+		return nil
+	}
+	signers := []*Signer{}
+	for _, k := range keys {
+		if string(k) == activeAccountKey(pk) {
+			continue
+		}
+		if s := c.loadSigner(string(k)); s != nil {
+			signers = append(signers, s)
+		}
+	}
+	sort.Slice(signers, func(i, j int) bool {
+		return signers[i].Username < signers[j].Username
 	})
+	return signers
 }
 
-func GetSigner(pk string) *Signer {
-	mu.RLock()
-	signer, ok := cache[pk]
-	mu.RUnlock()
+// UseSigner makes the account with fid the active one for the public key.
+func (c *Client) UseSigner(pk string, fid uint64) (*Signer, error) {
+	s := c.loadSigner(accountKey(pk, fid))
+	if s == nil {
+		return nil, ErrSignerNotFound
+	}
+	if err := c.store.Set([]byte(activeAccountKey(pk)), []byte(strconv.FormatUint(fid, 10))); err != nil {
+		return nil, err
+	}
+	c.signersMu.Lock()
+	c.active[pk] = fid
+	c.signersMu.Unlock()
+	return s, nil
+}
+
+// RemoveSigner deletes the account. If it was active, another account for
+// the public key becomes active, if there is one.
+func (c *Client) RemoveSigner(pk string, fid uint64) error {
+	key := accountKey(pk, fid)
+	if err := c.store.Delete([]byte(key)); err != nil {
+		return err
+	}
+	c.signersMu.Lock()
+	delete(c.signers, key)
+	active, ok := c.active[pk]
+	c.signersMu.Unlock()
+
+	if ok && active != fid {
+		return nil
+	}
+	if d, err := c.store.Get([]byte(activeAccountKey(pk))); err == nil && string(d) != strconv.FormatUint(fid, 10) {
+		return nil
+	}
+	c.signersMu.Lock()
+	delete(c.active, pk)
+	c.signersMu.Unlock()
+	if err := c.store.Delete([]byte(activeAccountKey(pk))); err != nil {
+		return err
+	}
+	if rest := c.Signers(pk); len(rest) > 0 {
+		_, err := c.UseSigner(pk, rest[0].FID)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) loadSigner(key string) *Signer {
+	c.signersMu.RLock()
+	signer, ok := c.signers[key]
+	k := c.signerKey
+	c.signersMu.RUnlock()
 	if ok {
 		return signer
 	}
-	key := fmt.Sprintf("signer:%s", pk)
-	d, err := db.GetDB().Get([]byte(key))
+	if k == nil {
+		slog.Debug("signer storage is locked")
+		return nil
+	}
+	d, err := c.store.Get([]byte(key))
+	if err != nil {
+		return nil
+	}
+	var sealed sealedSigner
+	if err := json.Unmarshal(d, &sealed); err != nil || len(sealed.Sealed) == 0 {
+		// a corrupt signer can't be recovered, drop it so the user signs in again
+		slog.Warn("dropping corrupt signer", "error", err)
+		_ = c.store.Delete([]byte(key))
+		return nil
+	}
+	d, err = k.open(sealed.Sealed)
 	if err != nil {
-		log.Println("no signer found in db")
+		slog.Error("failed to decrypt signer", "error", err)
 		return nil
 	}
 	signer = &Signer{}
 	if err = json.Unmarshal(d, signer); err != nil {
-		log.Println("failed to unmarshal signer: ", err)
+		slog.Warn("dropping corrupt signer", "error", err)
+		_ = c.store.Delete([]byte(key))
 		return nil
 	}
-	mu.Lock()
-	cache[pk] = signer
-	mu.Unlock()
+	c.signersMu.Lock()
+	c.signers[key] = signer
+	c.signersMu.Unlock()
 	return signer
 }
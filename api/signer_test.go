@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/treethought/tofui/api/neynartest"
+)
+
+func TestSignerEncryptedAtRest(t *testing.T) {
+	c, _ := newTestClient(t)
+	if err := c.SetSigner(&Signer{FID: 1, UUID: neynartest.SignerUUID, PublicKey: "local"}); !errors.Is(err, ErrSignersLocked) {
+		t.Fatalf("expected locked signer storage, got %v", err)
+	}
+
+	k, err := UnlockWithPassphrase(c.Store(), []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetSignerKey(k, AllSigners)
+	if err := c.SetSigner(&Signer{FID: 1, UUID: neynartest.SignerUUID, PublicKey: "local"}); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := c.Store().Get([]byte("signer:local:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), neynartest.SignerUUID) {
+		t.Error("signer uuid stored in plaintext")
+	}
+
+	if _, err := UnlockWithPassphrase(c.Store(), []byte("wrong")); !errors.Is(err, ErrBadPassphrase) {
+		t.Fatalf("expected bad passphrase, got %v", err)
+	}
+	k, err = UnlockWithPassphrase(c.Store(), []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a fresh client has no cached signers, so this decrypts from the store
+	other := NewClient(cfgFor(c), c.Store())
+	other.SetSignerKey(k, AllSigners)
+	s := other.GetSigner("local")
+	if s == nil || s.UUID != neynartest.SignerUUID {
+		t.Fatalf("expected decrypted signer, got %+v", s)
+	}
+}
+
+func TestPlaintextSignerMigrated(t *testing.T) {
+	c, _ := newTestClient(t)
+	d, _ := json.Marshal(&Signer{FID: 1, UUID: neynartest.SignerUUID, PublicKey: "local"})
+	if err := c.Store().Set([]byte("signer:local"), d); err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetSignerKey(NewRandomSignerKey(), AllSigners)
+
+	if _, err := c.Store().Get([]byte("signer:local")); err == nil {
+		t.Error("expected legacy signer record to be removed")
+	}
+	raw, err := c.Store().Get([]byte("signer:local:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), neynartest.SignerUUID) {
+		t.Error("expected plaintext signer to be encrypted")
+	}
+	if s := c.GetSigner("local"); s == nil || s.FID != 1 {
+		t.Fatalf("expected migrated signer, got %+v", s)
+	}
+}
+
+func TestSignerMigrationScope(t *testing.T) {
+	c, _ := newTestClient(t)
+	for _, pk := range []string{"local", "sshkey"} {
+		d, _ := json.Marshal(&Signer{FID: 1, UUID: neynartest.SignerUUID, PublicKey: pk})
+		if err := c.Store().Set([]byte("signer:"+pk), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the passphrase must not encrypt signers the server's key file reads
+	c.SetSignerKey(NewRandomSignerKey(), LocalSigners)
+	if s := c.GetSigner("local"); s == nil {
+		t.Error("expected the local signer to be migrated")
+	}
+	if _, err := c.Store().Get([]byte("signer:sshkey")); err != nil {
+		t.Errorf("expected the ssh signer to be left for the server key, got %v", err)
+	}
+
+	c.SetSignerKey(NewRandomSignerKey(), SSHSigners)
+	if s := c.GetSigner("sshkey"); s == nil {
+		t.Error("expected the ssh signer to be migrated by the server key")
+	}
+}
+
+func TestMultipleAccounts(t *testing.T) {
+	c, _ := newTestClient(t)
+	c.SetSignerKey(NewRandomSignerKey(), AllSigners)
+
+	if c.GetSigner("local") != nil {
+		t.Fatal("expected no signer before sign in")
+	}
+	for _, s := range []*Signer{
+		{FID: 2, Username: "alice", PublicKey: "local"},
+		{FID: 3, Username: "bob", PublicKey: "local"},
+		{FID: 4, Username: "carol", PublicKey: "other"},
+	} {
+		if err := c.SetSigner(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	accounts := c.Signers("local")
+	if len(accounts) != 2 || accounts[0].Username != "alice" || accounts[1].Username != "bob" {
+		t.Fatalf("unexpected accounts: %+v", accounts)
+	}
+	if s := c.GetSigner("local"); s == nil || s.FID != 3 {
+		t.Fatalf("expected last signed in account to be active, got %+v", s)
+	}
+
+	if _, err := c.UseSigner("local", 2); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.GetSigner("local"); s.FID != 2 {
+		t.Errorf("expected alice to be active, got %s", s.Username)
+	}
+	if _, err := c.UseSigner("local", 4); !errors.Is(err, ErrSignerNotFound) {
+		t.Errorf("expected other public key's account to be unavailable, got %v", err)
+	}
+
+	if err := c.RemoveSigner("local", 2); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.GetSigner("local"); s == nil || s.FID != 3 {
+		t.Fatalf("expected bob to become active, got %+v", s)
+	}
+	if err := c.RemoveSigner("local", 3); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.GetSigner("local"); s != nil {
+		t.Errorf("expected no active account, got %s", s.Username)
+	}
+	if s := c.GetSigner("other"); s == nil || s.FID != 4 {
+		t.Errorf("expected other public key to be unaffected, got %+v", s)
+	}
+}
+
+func TestLookupSigner(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	s, err := c.LookupSigner(neynartest.SignerUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != "approved" || s.FID != 1 {
+		t.Errorf("unexpected signer status: %+v", s)
+	}
+	if s, _ := c.LookupSigner(neynartest.RevokedSignerUUID); s == nil || s.Status != "revoked" {
+		t.Errorf("expected revoked signer, got %+v", s)
+	}
+	if _, err := c.LookupSigner("not-a-signer"); err == nil {
+		t.Error("expected error for unknown signer")
+	}
+}
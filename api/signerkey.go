@@ -0,0 +1,143 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/treethought/tofui/db"
+)
+
+// signerKeyMeta holds the passphrase salt and a sealed known value used to
+// check the passphrase on unlock.
+const signerKeyMeta = "meta:signerkey"
+
+var ErrBadPassphrase = errors.New("incorrect passphrase")
+
+// SignerKey encrypts signer records at rest with NaCl secretbox.
+type SignerKey struct {
+	key [32]byte
+}
+
+// NewRandomSignerKey returns a key that only lives as long as the process.
+func NewRandomSignerKey() *SignerKey {
+	k := &SignerKey{}
+	if _, err := io.ReadFull(rand.Reader, k.key[:]); err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// LoadSignerKeyFile reads a 32 byte key from path, creating the file with a
+// random key if it does not exist.
+func LoadSignerKeyFile(path string) (*SignerKey, error) {
+	d, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		k := NewRandomSignerKey()
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, k.key[:], 0600); err != nil {
+			return nil, fmt.Errorf("failed to write signer key file: %w", err)
+		}
+		return k, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signer key file: %w", err)
+	}
+	if len(d) != 32 {
+		return nil, fmt.Errorf("signer key file %s must contain exactly 32 bytes", path)
+	}
+	k := &SignerKey{}
+	copy(k.key[:], d)
+	return k, nil
+}
+
+type signerKeyCheck struct {
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+// HasPassphrase reports whether a passphrase has been set for the store.
+func HasPassphrase(store db.Store) bool {
+	_, err := store.Get([]byte(signerKeyMeta))
+	return err == nil
+}
+
+// UnlockWithPassphrase derives the signer key from passphrase. The first
+// time it is called for a store the passphrase is set, after that a wrong
+// passphrase returns ErrBadPassphrase.
+func UnlockWithPassphrase(store db.Store, passphrase []byte) (*SignerKey, error) {
+	var meta signerKeyCheck
+	d, err := store.Get([]byte(signerKeyMeta))
+	if err == nil {
+		if err := json.Unmarshal(d, &meta); err != nil {
+			return nil, fmt.Errorf("failed to read signer key check: %w", err)
+		}
+		k, err := deriveSignerKey(passphrase, meta.Salt)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := k.open(meta.Check); err != nil {
+			return nil, ErrBadPassphrase
+		}
+		return k, nil
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+
+	meta.Salt = make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, meta.Salt); err != nil {
+		return nil, err
+	}
+	k, err := deriveSignerKey(passphrase, meta.Salt)
+	if err != nil {
+		return nil, err
+	}
+	meta.Check = k.seal([]byte("tofui"))
+	d, _ = json.Marshal(meta)
+	if err := store.Set([]byte(signerKeyMeta), d); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func deriveSignerKey(passphrase, salt []byte) (*SignerKey, error) {
+	d, err := scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	k := &SignerKey{}
+	copy(k.key[:], d)
+	return k, nil
+}
+
+// seal encrypts plain, prefixing the result with its random nonce.
+func (k *SignerKey) seal(plain []byte) []byte {
+	var nonce [24]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		panic(err)
+	}
+	return secretbox.Seal(nonce[:], plain, &nonce, &k.key)
+}
+
+func (k *SignerKey) open(box []byte) ([]byte, error) {
+	if len(box) < 24 {
+		return nil, errors.New("sealed signer too short")
+	}
+	var nonce [24]byte
+	copy(nonce[:], box[:24])
+	plain, ok := secretbox.Open(nil, box[24:], &nonce, &k.key)
+	if !ok {
+		return nil, errors.New("failed to decrypt signer")
+	}
+	return plain, nil
+}
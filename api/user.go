@@ -1,12 +1,9 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"log"
-
-	"github.com/treethought/tofui/db"
+	"log/slog"
 )
 
 type Profile struct {
@@ -44,18 +41,38 @@ type User struct {
 	ViewerContext     ViewerContext     `json:"viewer_context"`
 }
 
+// userKey names a cached user. Users fetched for a viewer carry whether the
+// viewer follows them, so each viewer gets their own entry.
+func userKey(fid, viewer uint64) string {
+	if viewer == 0 {
+		return fmt.Sprintf("user:%d", fid)
+	}
+	return fmt.Sprintf("user:%d:%d", fid, viewer)
+}
+
 func (c *Client) GetUserByFID(fid uint64, viewer uint64) (*User, error) {
-	key := fmt.Sprintf("user:%d", fid)
-	cached, err := db.GetDB().Get([]byte(key))
+	key := userKey(fid, viewer)
+	cached, fresh, err := c.store.GetFresh([]byte(key))
 	if err == nil {
 		u := &User{}
 		if err := json.Unmarshal(cached, u); err != nil {
-			log.Fatal("failed to unmarshal cached user: ", err)
+			slog.Warn("dropping corrupt cached user", "key", key, "error", err)
+			_ = c.store.Delete([]byte(key))
+			return c.fetchUser(fid, viewer)
+		}
+		slog.Debug("got cached user", "fid", fid)
+		if !fresh {
+			c.revalidate(key, func() error {
+				_, err := c.fetchUser(fid, viewer)
+				return err
+			})
 		}
-		log.Println("got cached user: ", u.Username)
 		return u, nil
 	}
+	return c.fetchUser(fid, viewer)
+}
 
+func (c *Client) fetchUser(fid uint64, viewer uint64) (*User, error) {
 	path := "/user/bulk"
 
 	opts := []RequestOption{
@@ -66,7 +83,7 @@ func (c *Client) GetUserByFID(fid uint64, viewer uint64) (*User, error) {
 	}
 
 	var resp BulkUsersResponse
-	if err := c.doRequestInto(context.TODO(), path, &resp, opts...); err != nil {
+	if err := c.doRequestInto(c.ctx, path, &resp, opts...); err != nil {
 		return nil, err
 	}
 	if len(resp.Users) == 0 {
@@ -74,8 +91,8 @@ func (c *Client) GetUserByFID(fid uint64, viewer uint64) (*User, error) {
 	}
 	user := resp.Users[0]
 	d, _ := json.Marshal(user)
-	if err := db.GetDB().Set([]byte(key), []byte(d)); err != nil {
-		log.Println("failed to cache user: ", err)
+	if err := c.store.Set([]byte(userKey(fid, viewer)), []byte(d)); err != nil {
+		slog.Error("failed to cache user", "error", err)
 	}
 	return user, nil
 }
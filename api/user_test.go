@@ -0,0 +1,98 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/treethought/tofui/db"
+)
+
+func TestGetUserByFIDCaches(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	u, err := c.GetUserByFID(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "alice" || !u.ViewerContext.Following {
+		t.Fatalf("unexpected user: %+v", u)
+	}
+
+	cached, err := c.GetUserByFID(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.Username != "alice" {
+		t.Errorf("expected cached alice, got %s", cached.Username)
+	}
+	if hits := srv.Hits("/user/bulk"); hits != 1 {
+		t.Errorf("expected second lookup to be served from cache, got %d requests", hits)
+	}
+}
+
+func TestGetUserByFIDNotFound(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	if _, err := c.GetUserByFID(999, 0); err == nil {
+		t.Fatal("expected error for unknown fid")
+	}
+}
+
+func TestGetUserByFIDRevalidatesStale(t *testing.T) {
+	c, srv := newTestClient(t)
+	c.Store().SetPolicy("user:", db.Policy{TTL: time.Nanosecond, Stale: time.Hour})
+
+	if _, err := c.GetUserByFID(2, 1); err != nil {
+		t.Fatal(err)
+	}
+	// stale entries are served immediately and refreshed once in the background
+	for i := 0; i < 3; i++ {
+		u, err := c.GetUserByFID(2, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Username != "alice" {
+			t.Fatalf("expected stale alice, got %s", u.Username)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.Hits("/user/bulk") < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hits := srv.Hits("/user/bulk"); hits < 2 || hits > 4 {
+		t.Errorf("expected stale lookups to trigger a refresh, got %d requests", hits)
+	}
+}
+
+func TestGetUserByFIDPerViewer(t *testing.T) {
+	c, srv := newTestClient(t)
+
+	for _, viewer := range []uint64{1, 3, 1} {
+		if _, err := c.GetUserByFID(2, viewer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// whether the viewer follows the user differs between viewers
+	if hits := srv.Hits("/user/bulk"); hits != 2 {
+		t.Errorf("expected one request per viewer, got %d", hits)
+	}
+}
+
+func TestGetUserByFIDCorruptEntry(t *testing.T) {
+	c, srv := newTestClient(t)
+	if err := c.Store().Set([]byte("user:2:1"), []byte("{not json")); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := c.GetUserByFID(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "alice" {
+		t.Errorf("expected refetched alice, got %s", u.Username)
+	}
+	if hits := srv.Hits("/user/bulk"); hits != 1 {
+		t.Errorf("expected corrupt entry to be refetched, got %d requests", hits)
+	}
+}
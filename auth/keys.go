@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// KeyPolicy decides which SSH public keys may connect. Denied keys are
+// always rejected. When an allowlist or authorized_keys file is set, only
+// keys found in one of them are accepted, otherwise any key is.
+type KeyPolicy struct {
+	allow          KeyList
+	deny           KeyList
+	authorizedKeys string
+}
+
+// NewKeyPolicy parses allow and deny, which hold keys in authorized_keys
+// format. authorizedKeys is read on every check so edits apply to new
+// connections without a restart.
+func NewKeyPolicy(allow, deny []string, authorizedKeys string) (*KeyPolicy, error) {
+	p := &KeyPolicy{authorizedKeys: authorizedKeys}
+	var err error
+	if p.allow, err = ParseKeyList(allow); err != nil {
+		return nil, fmt.Errorf("invalid allowed key: %w", err)
+	}
+	if p.deny, err = ParseKeyList(deny); err != nil {
+		return nil, fmt.Errorf("invalid denied key: %w", err)
+	}
+	if authorizedKeys != "" {
+		if _, err := os.Stat(authorizedKeys); err != nil {
+			return nil, fmt.Errorf("failed to read authorized keys: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// KeyHash identifies the owner of an SSH public key. Signers for SSH users
+// are stored under it.
+func KeyHash(key gossh.PublicKey) string {
+	return fmt.Sprintf("%x", sha256.Sum256(key.Marshal()))
+}
+
+// KeyList is a set of public keys.
+type KeyList [][]byte
+
+// ParseKeyList parses keys in authorized_keys format.
+func ParseKeyList(keys []string) (KeyList, error) {
+	parsed := make(KeyList, 0, len(keys))
+	for _, k := range keys {
+		pk, _, _, _, err := gossh.ParseAuthorizedKey([]byte(k))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", k, err)
+		}
+		parsed = append(parsed, pk.Marshal())
+	}
+	return parsed, nil
+}
+
+// Allowed reports whether key may connect.
+func (p *KeyPolicy) Allowed(key gossh.PublicKey) bool {
+	if p.deny.Contains(key) {
+		return false
+	}
+	if len(p.allow) == 0 && p.authorizedKeys == "" {
+		return true
+	}
+	return p.allow.Contains(key) || p.authorized(key.Marshal())
+}
+
+func (p *KeyPolicy) authorized(k []byte) bool {
+	if p.authorizedKeys == "" {
+		return false
+	}
+	f, err := os.Open(p.authorizedKeys)
+	if err != nil {
+		slog.Error("failed to read authorized keys", "error", err)
+		return false
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		pk, _, _, _, err := gossh.ParseAuthorizedKey(line)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(pk.Marshal(), k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains reports whether key is in the list.
+func (l KeyList) Contains(key gossh.PublicKey) bool {
+	k := key.Marshal()
+	for _, listed := range l {
+		if bytes.Equal(listed, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func newKey(t *testing.T) (gossh.PublicKey, string) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pk, string(gossh.MarshalAuthorizedKey(pk))
+}
+
+func TestKeyPolicy(t *testing.T) {
+	alice, aliceLine := newKey(t)
+	bob, bobLine := newKey(t)
+	carol, carolLine := newKey(t)
+
+	open, err := NewKeyPolicy(nil, []string{bobLine}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !open.Allowed(alice) || open.Allowed(bob) {
+		t.Error("expected any key but the denied one to be allowed")
+	}
+
+	path := filepath.Join(t.TempDir(), "authorized_keys")
+	if err := os.WriteFile(path, []byte("# team\n"+carolLine), 0600); err != nil {
+		t.Fatal(err)
+	}
+	closed, err := NewKeyPolicy([]string{aliceLine, bobLine}, []string{bobLine}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !closed.Allowed(alice) || !closed.Allowed(carol) {
+		t.Error("expected allowed and authorized keys to be allowed")
+	}
+	if closed.Allowed(bob) {
+		t.Error("expected deny to win over allow")
+	}
+	stranger, _ := newKey(t)
+	if closed.Allowed(stranger) {
+		t.Error("expected unlisted key to be rejected")
+	}
+
+	if _, err := NewKeyPolicy([]string{"not a key"}, nil, ""); err == nil {
+		t.Error("expected invalid key to be rejected")
+	}
+}
@@ -0,0 +1,152 @@
+// Package auth holds state shared between the TUI and the sign in HTTP
+// server, and decides who may connect over SSH.
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codeAlphabet leaves out vowels and characters that are easy to confuse, so
+// device codes are easy to read off a screen and type on a phone.
+const codeAlphabet = "BCDFGHJKLMNPQRSTVWXZ23456789"
+
+type nonce struct {
+	pk      string
+	code    string
+	expires time.Time
+	used    bool
+	fid     uint64
+}
+
+// Nonces issues one-time sign in nonces, each bound to the public key of the
+// session that requested it. The nonce is only shown in that session, so a
+// sign in callback carrying it must have been started from there.
+type Nonces struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	nonces map[string]*nonce
+	codes  map[string]string
+}
+
+func NewNonces(ttl time.Duration) *Nonces {
+	return &Nonces{
+		ttl:    ttl,
+		nonces: make(map[string]*nonce),
+		codes:  make(map[string]string),
+	}
+}
+
+// Issue returns a new nonce for the public key, along with a short device
+// code that can be exchanged for it with Lookup.
+func (n *Nonces) Issue(pk string) (string, string) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	v := hex.EncodeToString(b)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	for k, e := range n.nonces {
+		if now.After(e.expires) {
+			delete(n.codes, e.code)
+			delete(n.nonces, k)
+		}
+	}
+	code := newCode()
+	for _, taken := n.codes[code]; taken; _, taken = n.codes[code] {
+		code = newCode()
+	}
+	n.nonces[v] = &nonce{pk: pk, code: code, expires: now.Add(n.ttl)}
+	n.codes[code] = v
+	return v, code
+}
+
+func newCode() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b[:4]) + "-" + string(b[4:])
+}
+
+// Lookup returns the nonce a device code was issued with. Codes are matched
+// ignoring case, spaces and the dash.
+func (n *Nonces) Lookup(code string) (string, bool) {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 8 {
+		return "", false
+	}
+	code = code[:4] + "-" + code[4:]
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	v, ok := n.codes[code]
+	if !ok {
+		return "", false
+	}
+	e := n.nonces[v]
+	if e.used || time.Now().After(e.expires) {
+		return "", false
+	}
+	return v, true
+}
+
+// Valid reports whether the nonce was issued and has not expired or been
+// used, without using it.
+func (n *Nonces) Valid(v string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	e, ok := n.nonces[v]
+	return ok && !e.used && time.Now().Before(e.expires)
+}
+
+// Consume uses the nonce, returning the public key it was issued for. A
+// nonce can only be consumed once.
+func (n *Nonces) Consume(v string) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	e, ok := n.nonces[v]
+	if !ok || e.used || time.Now().After(e.expires) {
+		return "", false
+	}
+	e.used = true
+	return e.pk, true
+}
+
+// Pending reports whether the nonce can still complete a sign in.
+func (n *Nonces) Pending(v string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	e, ok := n.nonces[v]
+	return ok && e.fid == 0 && time.Now().Before(e.expires)
+}
+
+// Complete records that the sign in started with the nonce finished for fid.
+func (n *Nonces) Complete(v string, fid uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if e, ok := n.nonces[v]; ok {
+		e.fid = fid
+	}
+}
+
+// Completed returns the fid signed in with the nonce, once the sign in has
+// finished.
+func (n *Nonces) Completed(v string) (uint64, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	e, ok := n.nonces[v]
+	if !ok || e.fid == 0 {
+		return 0, false
+	}
+	return e.fid, true
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNonces(t *testing.T) {
+	n := NewNonces(time.Minute)
+	a, _ := n.Issue("pk-a")
+	b, _ := n.Issue("pk-b")
+	if a == b {
+		t.Fatal("expected unique nonces")
+	}
+	if !n.Valid(a) {
+		t.Fatal("expected issued nonce to be valid")
+	}
+
+	pk, ok := n.Consume(a)
+	if !ok || pk != "pk-a" {
+		t.Fatalf("expected nonce for pk-a, got %q %v", pk, ok)
+	}
+	if _, ok := n.Consume(a); ok {
+		t.Error("expected nonce to be single use")
+	}
+	if n.Valid(a) {
+		t.Error("expected used nonce to be invalid")
+	}
+	if _, ok := n.Consume("made-up"); ok {
+		t.Error("expected unknown nonce to be rejected")
+	}
+	if pk, _ := n.Consume(b); pk != "pk-b" {
+		t.Errorf("expected nonce for pk-b, got %q", pk)
+	}
+}
+
+func TestNonceExpiry(t *testing.T) {
+	n := NewNonces(time.Millisecond)
+	v, _ := n.Issue("pk")
+	time.Sleep(5 * time.Millisecond)
+	if n.Valid(v) {
+		t.Error("expected expired nonce to be invalid")
+	}
+	if _, ok := n.Consume(v); ok {
+		t.Error("expected expired nonce to be rejected")
+	}
+}
+
+func TestDeviceCode(t *testing.T) {
+	n := NewNonces(time.Minute)
+	v, code := n.Issue("pk")
+	if len(code) != 9 || code[4] != '-' {
+		t.Fatalf("unexpected code format %q", code)
+	}
+	for _, c := range []string{code, strings.ToLower(code), strings.ReplaceAll(code, "-", " ")} {
+		got, ok := n.Lookup(c)
+		if !ok || got != v {
+			t.Errorf("expected %q to resolve to nonce, got %q %v", c, got, ok)
+		}
+	}
+	if _, ok := n.Lookup("BBBB-BBBB"); ok {
+		t.Error("expected unknown code to be rejected")
+	}
+
+	if _, ok := n.Completed(v); ok {
+		t.Error("expected sign in to be pending")
+	}
+	n.Consume(v)
+	if _, ok := n.Lookup(code); ok {
+		t.Error("expected code for used nonce to be rejected")
+	}
+	n.Complete(v, 42)
+	if fid, ok := n.Completed(v); !ok || fid != 42 {
+		t.Errorf("expected sign in completed for 42, got %d %v", fid, ok)
+	}
+}
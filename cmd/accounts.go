@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/treethought/tofui/api"
+)
+
+var accountsPK string
+
+var accountsCmd = &cobra.Command{
+	Use:   "accounts",
+	Short: "manage signed in accounts",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// accounts of SSH users are encrypted with the server's key
+		if accountsPK == "local" {
+			unlockSigners()
+		} else {
+			unlockServerSigners()
+		}
+	},
+}
+
+var accountsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list signed in accounts, the active one is marked with *",
+	Run: func(cmd *cobra.Command, args []string) {
+		defer shutdown()
+		accounts := client.Signers(accountsPK)
+		if len(accounts) == 0 {
+			fmt.Println("no accounts signed in, run `tofui` to sign in")
+			return
+		}
+		active := client.GetSigner(accountsPK)
+		for _, s := range accounts {
+			mark := " "
+			if active != nil && active.FID == s.FID {
+				mark = "*"
+			}
+			fmt.Printf("%s @%s (fid %d)\n", mark, s.Username, s.FID)
+		}
+	},
+}
+
+var accountsUseCmd = &cobra.Command{
+	Use:          "use <username|fid>",
+	Short:        "make an account the active one",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer shutdown()
+		s, err := findAccount(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := client.UseSigner(accountsPK, s.FID); err != nil {
+			return fmt.Errorf("failed to switch account: %w", err)
+		}
+		fmt.Printf("now using @%s\n", s.Username)
+		return nil
+	},
+}
+
+var accountsRemoveCmd = &cobra.Command{
+	Use:          "remove <username|fid>",
+	Short:        "remove a signed in account",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer shutdown()
+		s, err := findAccount(args[0])
+		if err != nil {
+			return err
+		}
+		if err := client.RemoveSigner(accountsPK, s.FID); err != nil {
+			return fmt.Errorf("failed to remove account: %w", err)
+		}
+		fmt.Printf("removed @%s\n", s.Username)
+		return nil
+	},
+}
+
+// findAccount looks up an account by username, with or without the @, or
+// by fid.
+func findAccount(q string) (*api.Signer, error) {
+	q = strings.TrimPrefix(q, "@")
+	fid, _ := strconv.ParseUint(q, 10, 64)
+	for _, s := range client.Signers(accountsPK) {
+		if s.Username == q || (fid != 0 && s.FID == fid) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("no signed in account %s, see `tofui accounts list`", q)
+}
+
+func init() {
+	accountsCmd.PersistentFlags().StringVar(&accountsPK, "pk", "local", "public key hash the accounts belong to, for SSH users")
+	accountsCmd.AddCommand(accountsListCmd, accountsUseCmd, accountsRemoveCmd)
+	rootCmd.AddCommand(accountsCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+
+	"github.com/treethought/tofui/auth"
+	"github.com/treethought/tofui/ui"
+)
+
+const adminUsage = `admin commands:
+  sessions             list active sessions
+  broadcast <message>  show a message in every session, an empty message clears it`
+
+// adminMiddleware runs commands such as `ssh <host> sessions` for admin keys.
+// Everyone else falls through to the app.
+func (sv *Server) adminMiddleware(admins auth.KeyList) wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(s ssh.Session) {
+			cmd := s.Command()
+			if len(cmd) == 0 || s.PublicKey() == nil || !admins.Contains(s.PublicKey()) {
+				next(s)
+				return
+			}
+			switch cmd[0] {
+			case "sessions":
+				sv.printSessions(s)
+			case "broadcast":
+				n := sv.registry.broadcast(ui.BroadcastMsg{Text: strings.Join(cmd[1:], " ")})
+				wish.Printf(s, "sent to %d sessions\n", n)
+			default:
+				wish.Fatalln(s, adminUsage)
+			}
+		}
+	}
+}
+
+func (sv *Server) printSessions(s ssh.Session) {
+	w := tabwriter.NewWriter(s, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKEY\tUSER\tVIEW\tSTARTED\tREMOTE")
+	for _, sess := range sv.registry.list() {
+		var info ui.SessionInfo
+		if sess.app != nil {
+			info = sess.app.Info()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			sess.id, sess.pk[:12], info.Username, info.View,
+			sess.started.Format(time.RFC3339), sess.remote,
+		)
+	}
+	w.Flush()
+}
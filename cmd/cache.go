@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/treethought/tofui/db"
+)
+
+var (
+	pruneOlderThan time.Duration
+	prunePrefixes  []string
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "inspect and manage the local cache",
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "show entry counts and sizes per key prefix",
+	Run: func(cmd *cobra.Command, args []string) {
+		defer store.Close()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "PREFIX\tENTRIES\tSIZE\t")
+		var count, size int64
+		for _, prefix := range db.Prefixes {
+			var n, b int64
+			err := store.Scan([]byte(prefix), func(e db.EntryInfo) error {
+				n++
+				b += e.Size
+				return nil
+			})
+			if err != nil {
+				log.Fatal("failed to scan cache: ", err)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t\n", prefix, n, formatBytes(b))
+			count += n
+			size += b
+		}
+		fmt.Fprintf(w, "total\t%d\t%s\t\n", count, formatBytes(size))
+		w.Flush()
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "delete cached entries older than a given age",
+	Run: func(cmd *cobra.Command, args []string) {
+		defer store.Close()
+		if pruneOlderThan <= 0 {
+			log.Fatal("--older-than must be greater than zero")
+		}
+		for _, p := range prunePrefixes {
+			if p == "signer:" {
+				log.Fatal("signers can not be pruned, use `tofui cache clear` or sign out instead")
+			}
+		}
+		cutoff := time.Now().Add(-pruneOlderThan)
+		n := deleteEntries(prunePrefixes, func(e db.EntryInfo) bool {
+			return !e.Updated.IsZero() && e.Updated.Before(cutoff)
+		})
+		fmt.Printf("pruned %d entries\n", n)
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "delete all cached entries, keeping signers",
+	Run: func(cmd *cobra.Command, args []string) {
+		defer store.Close()
+		// scan everything so entries outside the known namespaces, such as
+		// channelsloaded, are removed too
+		n := deleteEntries([]string{""}, func(e db.EntryInfo) bool {
+			k := string(e.Key)
+			return !strings.HasPrefix(k, "signer:") && !strings.HasPrefix(k, "meta:")
+		})
+		fmt.Printf("cleared %d entries\n", n)
+	},
+}
+
+// cachePrefixes returns every namespace except signers, which hold
+// credentials rather than cached data.
+func cachePrefixes() []string {
+	prefixes := make([]string, 0, len(db.Prefixes))
+	for _, p := range db.Prefixes {
+		if p != "signer:" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// deleteEntries deletes entries under prefixes matching fn and reclaims
+// their disk space.
+func deleteEntries(prefixes []string, fn func(db.EntryInfo) bool) int {
+	n := 0
+	for _, prefix := range prefixes {
+		var keys [][]byte
+		err := store.Scan([]byte(prefix), func(e db.EntryInfo) error {
+			if fn(e) {
+				keys = append(keys, e.Key)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal("failed to scan cache: ", err)
+		}
+		for _, k := range keys {
+			if err := store.Delete(k); err != nil {
+				log.Fatal("failed to delete entry: ", err)
+			}
+		}
+		n += len(keys)
+	}
+	if bs, ok := store.(*db.BadgerStore); ok {
+		bs.Reclaim()
+	}
+	return n
+}
+
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	cachePruneCmd.Flags().DurationVar(&pruneOlderThan, "older-than", 0, "prune entries written longer ago than this, e.g. 72h")
+	cachePruneCmd.Flags().StringSliceVar(&prunePrefixes, "prefix", cachePrefixes(), "key prefixes to prune")
+	cachePruneCmd.MarkFlagRequired("older-than")
+
+	cacheCmd.AddCommand(cacheStatsCmd, cachePruneCmd, cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -1,15 +1,15 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 
-	"github.com/treethought/tofui/api"
-	"github.com/treethought/tofui/db"
 	"github.com/treethought/tofui/ui"
 )
 
@@ -17,26 +17,33 @@ var castCmd = &cobra.Command{
 	Use:   "cast",
 	Short: "publish a cast",
 	Run: func(cmd *cobra.Command, args []string) {
-		defer logFile.Close()
-		defer db.GetDB().Close()
-		signer := api.GetSigner("local")
-		if signer != nil {
-			log.Println("logged in as: ", signer.Username)
-		}
-		if signer == nil {
-			fmt.Println("please sign in to use this command by running `tofui`")
-			return
-		}
-
-		app := ui.NewLocalApp(cfg, true)
-		p := tea.NewProgram(app, tea.WithAltScreen())
-		if _, err := p.Run(); err != nil {
+		if err := runCast(cmd.Context()); err != nil {
 			fmt.Printf("Alas, there's been an error: %v", err)
 			os.Exit(1)
 		}
 	},
 }
 
+func runCast(ctx context.Context) error {
+	defer shutdown()
+	unlockSigners()
+	signer := client.GetSigner("local")
+	if signer != nil {
+		slog.Info("logged in", "fid", signer.FID)
+	}
+	if signer == nil {
+		fmt.Println("please sign in to use this command by running `tofui`")
+		return nil
+	}
+
+	app := ui.NewLocalApp(cfg, client, nil, true)
+	p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithContext(ctx))
+	if _, err := p.Run(); err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+		return err
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(castCmd)
 }
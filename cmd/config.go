@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/treethought/tofui/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "inspect the effective config",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "print the config in effect, with secrets masked",
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := loadConfig()
+		if err != nil {
+			log.Fatal("failed to read config: ", err)
+		}
+		d, err := yaml.Marshal(c.Masked())
+		if err != nil {
+			log.Fatal("failed to encode config: ", err)
+		}
+		fmt.Println("# config from", configSource())
+		for _, name := range config.EnvVars() {
+			if _, ok := os.LookupEnv(name); ok {
+				fmt.Println("# overridden by", name)
+			}
+		}
+		fmt.Print(string(d))
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "check the config for problems",
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := loadConfig()
+		if err != nil {
+			log.Fatal("failed to read config: ", err)
+		}
+		if err := validateConfig(c); err != nil {
+			fmt.Fprintf(os.Stderr, "config from %s is invalid:\n%s\n", configSource(), err)
+			os.Exit(1)
+		}
+		fmt.Printf("config from %s is valid\n", configSource())
+	},
+}
+
+func configSource() string {
+	if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
+		return fmt.Sprintf("environment and defaults, no file at %s", configPath)
+	}
+	return configPath
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
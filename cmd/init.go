@@ -1,67 +1,112 @@
 package cmd
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 
+	"github.com/treethought/tofui/api"
 	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
+	"github.com/treethought/tofui/ui"
 )
 
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "init tofui config",
 	Run: func(cmd *cobra.Command, args []string) {
-		home, err := os.UserHomeDir()
+		// nothing is logged to the terminal while the wizard is drawn
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		path := configPath
+		if path == "" {
+			path = filepath.Join(config.ConfigDir(), "config.yaml")
+		}
+		defaults, exists, err := initDefaults(path)
 		if err != nil {
-			log.Fatal("failed to get user home directory")
+			log.Fatal("failed to read existing config: ", err)
 		}
-		fmt.Println("To use tofui locally, you will need to create a Neynar app")
-		reader := bufio.NewReader(os.Stdin)
-
-		fmt.Print("Enter Client ID (found at https://dev.neynar.com/app): \n")
-		clientID, _ := reader.ReadString('\n')
-		clientID = clientID[:len(clientID)-1] // Trim newline character
-
-		fmt.Print("Enter API Key (found at https://dev.neynar.com/): \n")
-		apiKey, _ := reader.ReadString('\n')
-		apiKey = apiKey[:len(apiKey)-1] // Trim newline character
 
-		cfg := &config.Config{}
-		cfg.Neynar.ClientID = clientID
-		cfg.Neynar.APIKey = apiKey
-		cfg.Neynar.BaseUrl = "https://api.neynar.com/v2/farcaster"
-		cfg.Server.Host = "localhost"
-		cfg.Server.HTTPPort = 4200
-		cfg.DB.Dir = filepath.Join(home, ".tofui", "db")
-		cfg.Log.Path = filepath.Join(home, ".tofui", "debug.log")
-
-		path := filepath.Join(home, ".tofui", "config.yaml")
-
-		data, err := yaml.Marshal(cfg)
-		if err != nil {
-			log.Fatalf("error: %v", err)
+		w := ui.NewInitWizard(path, defaults, exists, verifyAPIKey)
+		if _, err := tea.NewProgram(w, tea.WithContext(cmd.Context())).Run(); err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+			log.Fatal("setup failed: ", err)
 		}
-		err = os.MkdirAll(filepath.Dir(path), 0755)
-		if err != nil {
-			log.Fatalf("failed to create config directory: %v", err)
+		c, ok := w.Result()
+		if !ok {
+			fmt.Println("Setup cancelled, no config was written")
+			return
 		}
-		if err = os.WriteFile(path, data, 0644); err != nil {
-			log.Fatalf("error: %v", err)
+		if err := writeConfig(path, c); err != nil {
+			log.Fatal(err)
 		}
-		fmt.Printf("Wrote config file created at %s\n", path)
-		fmt.Println("Note: You must add 'http://localhost:4200' to your Neynar app's Authorzed origins to sign in!!")
+		fmt.Printf("Wrote config to %s\n", path)
+		fmt.Printf("Note: You must add '%s' to your Neynar app's authorized origins to sign in!\n", c.BaseURL())
 		fmt.Println("\nYou can now run `tofui` to start the app")
-
 	},
 }
 
+// initDefaults prefills the wizard from the existing config, if there is
+// one, with tofui's defaults for anything unset.
+func initDefaults(path string) (*config.Config, bool, error) {
+	c, err := config.ReadConfig(path)
+	exists := err == nil
+	if errors.Is(err, os.ErrNotExist) {
+		c = &config.Config{}
+	} else if err != nil {
+		return nil, false, err
+	}
+	if c.Neynar.BaseUrl == "" {
+		c.Neynar.BaseUrl = config.DefaultBaseURL
+	}
+	if c.Server.Host == "" {
+		c.Server.Host = "localhost"
+	}
+	if c.Server.HTTPPort == 0 {
+		c.Server.HTTPPort = 4200
+	}
+	if c.Server.SSHPort == 0 {
+		c.Server.SSHPort = defaultSSHPort
+	}
+	if c.DB.Dir == "" {
+		c.DB.Dir = filepath.Join(config.DataDir(), "db")
+	}
+	if c.Log.Path == "" {
+		c.Log.Path = filepath.Join(config.CacheDir(), "tofui.log")
+	}
+	return c, exists, nil
+}
+
+func verifyAPIKey(c *config.Config) error {
+	client := api.NewClient(c, db.NewMemoryStore(c))
+	defer client.Close()
+	return client.CheckAPIKey()
+}
+
+// writeConfig writes c to path readable only by the user, as it holds the
+// API key.
+func writeConfig(path string, c *config.Config) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	// WriteFile keeps the mode of a file that already exists
+	return os.Chmod(path, 0600)
+}
+
 func init() {
 	rootCmd.AddCommand(initCmd)
-
 }
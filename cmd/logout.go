@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var logoutAll bool
+
+var logoutCmd = &cobra.Command{
+	Use:          "logout",
+	Short:        "sign out of the active account",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defer shutdown()
+		unlockSigners()
+
+		signers := client.Signers("local")
+		if !logoutAll {
+			signers = signers[:0]
+			if s := client.GetSigner("local"); s != nil {
+				signers = append(signers, s)
+			}
+		}
+		if len(signers) == 0 {
+			fmt.Println("not signed in")
+			return nil
+		}
+		for _, s := range signers {
+			if err := client.RemoveSigner("local", s.FID); err != nil {
+				return fmt.Errorf("failed to sign out: %w", err)
+			}
+			fmt.Printf("signed out of @%s\n", s.Username)
+		}
+		if next := client.GetSigner("local"); next != nil {
+			fmt.Printf("now using @%s\n", next.Username)
+		}
+		return nil
+	},
+}
+
+func init() {
+	logoutCmd.Flags().BoolVar(&logoutAll, "all", false, "sign out of every account")
+	rootCmd.AddCommand(logoutCmd)
+}
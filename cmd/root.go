@@ -1,102 +1,197 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 
+	"github.com/treethought/tofui/api"
 	"github.com/treethought/tofui/config"
 	"github.com/treethought/tofui/db"
+	"github.com/treethought/tofui/logging"
 	"github.com/treethought/tofui/ui"
 )
 
 var (
-	configPath = os.Getenv("CONFIG_FILE")
+	configPath string
 	cfg        *config.Config
-	logFile    *os.File
+	logFile    io.Closer
+
+	recordPath string
+	replayPath string
+	recorder   *api.Recorder
+	replayer   *api.Replayer
+
+	noCache bool
+	store   db.Store
+	client  *api.Client
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "tofui",
 	Short: "terminally on farcaster user interface",
 	Run: func(cmd *cobra.Command, args []string) {
-		runLocal()
-
+		if err := runLocal(cmd.Context()); err != nil {
+			fmt.Printf("Alas, there's been an error: %v", err)
+			os.Exit(1)
+		}
 	},
 }
 
-func runLocal() {
-	defer logFile.Close()
-	defer db.GetDB().Close()
-	sv := &Server{
-		prgmSessions: make(map[string][]*tea.Program),
-	}
-	go sv.startSigninHTTPServer()
-	app := ui.NewLocalApp(cfg, false)
-	p := tea.NewProgram(app, tea.WithAltScreen())
-	sv.prgmSessions["local"] = append(sv.prgmSessions["local"], p)
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Alas, there's been an error: %v", err)
-		os.Exit(1)
+func runLocal(ctx context.Context) error {
+	defer shutdown()
+	unlockSigners()
+	sv := NewServer()
+	srv := sv.startSigninHTTPServer()
+	defer stopHTTPServer(srv)
+	app := ui.NewLocalApp(cfg, client, sv.nonces, false)
+	p := tea.NewProgram(app, tea.WithAltScreen(), tea.WithContext(ctx))
+	sv.ready.Store(true)
+	if _, err := p.Run(); err != nil && !errors.Is(err, tea.ErrProgramKilled) {
+		return err
 	}
+	return nil
 }
 
+// Execute runs the root command. Commands are handed a context that is
+// cancelled on SIGINT or SIGTERM so they can shut down in order.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// shutdown cancels in-flight API calls and waits for background refreshes
+// before closing the db they write to.
+func shutdown() {
+	client.Close()
+	store.Close()
+	closeCassette()
+	logFile.Close()
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
-	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "config file (default is $HOME/.tofui.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "config file (default is ./config.yaml or $XDG_CONFIG_HOME/tofui/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "record all API traffic to a cassette file")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "serve API traffic from a cassette file instead of the network")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "keep the cache in memory only, nothing is read from or written to disk")
+}
+
+// initCassette installs a recording or replaying transport on the default
+// http client, which is shared by the api client and image fetching.
+func initCassette() {
+	switch {
+	case recordPath != "":
+		rec, err := api.NewRecorder(recordPath, http.DefaultTransport)
+		if err != nil {
+			log.Fatal("failed to create cassette: ", err)
+		}
+		recorder = rec
+		http.DefaultClient.Transport = rec
+		slog.Info("recording API traffic", "path", recordPath)
+	case replayPath != "":
+		rp, err := api.LoadCassette(replayPath)
+		if err != nil {
+			log.Fatal("failed to load cassette: ", err)
+		}
+		replayer = rp
+		http.DefaultClient.Transport = rp
+		slog.Info("replaying API traffic", "path", replayPath)
+	}
+}
+
+func closeCassette() {
+	if recorder != nil {
+		recorder.Close()
+	}
+}
+
+// standalone reports whether the command being run sets itself up, such as
+// init and config, which must work without a valid config.
+func standalone() bool {
+	c, _, err := rootCmd.Find(os.Args[1:])
+	if err != nil {
+		return false
+	}
+	for ; c != nil; c = c.Parent() {
+		if c == initCmd || c == configCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfig reads the config file, applying environment overrides. The
+// file may be missing if everything required is set in the environment.
+func loadConfig() (*config.Config, error) {
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_FILE")
+	}
+	if configPath == "" {
+		configPath = config.DefaultPath()
+	}
+	return config.Load(configPath)
+}
+
+// validateConfig checks the config along with the keys it remaps and its
+// theme, which the ui owns.
+func validateConfig(c *config.Config) error {
+	_, keysErr := ui.NewKeys(c.Keys)
+	_, themeErr := ui.NewTheme(c.Theme)
+	return errors.Join(c.Validate(), keysErr, themeErr)
 }
 
 func initConfig() {
-	if len(os.Args) > 1 && os.Args[1] == "init" {
+	if standalone() {
 		return
 	}
 	var err error
-	if configPath == "" {
-		if _, err := os.Stat("config.yaml"); err == nil {
-			configPath = "config.yaml"
-		} else {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				log.Fatal("failed to find default config file: ", err)
-			}
-			configPath = filepath.Join(homeDir, ".tofui", "config.yaml")
-		}
-	}
-	cfg, err = config.ReadConfig(configPath)
+	cfg, err = loadConfig()
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			log.Fatal("failed to fing config file, run `tofui init` to create one")
-		}
 		log.Fatal("failed to read config: ", err)
 	}
-
-	lf := cfg.Log.Path
-	if lf == "" {
-		lf = "tofui.log"
+	if err := validateConfig(cfg); err != nil {
+		if _, statErr := os.Stat(configPath); errors.Is(statErr, os.ErrNotExist) {
+			log.Fatalf("no config file at %s, run `tofui init` to create one", configPath)
+		}
+		log.Fatalf("invalid config %s:\n%s", configPath, err)
 	}
-	dir := filepath.Dir(lf)
-	err = os.MkdirAll(dir, 0755)
+
+	logFile, err = logging.Setup(cfg)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatal("failed to set up logging: ", err)
 	}
-	logFile, err = tea.LogToFile(lf, "debug")
+	slog.Info("loaded config", "path", configPath)
+	initCassette()
+	initStore()
+	client = api.NewClient(cfg, store)
+}
+
+func initStore() {
+	// replays only see what's in the cassette, and leave the cache alone
+	if noCache || replayPath != "" {
+		slog.Debug("using in-memory cache")
+		store = db.NewMemoryStore(cfg)
+		return
+	}
+	s, err := db.OpenBadger(cfg)
 	if err != nil {
 		log.Fatal(err)
-		os.Exit(1)
 	}
-	log.Println("loaded config: ", configPath)
-	db.InitDB(cfg)
-
+	store = s
 }
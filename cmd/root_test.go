@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/treethought/tofui/api"
+	"github.com/treethought/tofui/api/neynartest"
+	"github.com/treethought/tofui/config"
+)
+
+func TestReplaySignedIn(t *testing.T) {
+	srv := neynartest.NewServer()
+	t.Cleanup(srv.Close)
+	transport := http.DefaultClient.Transport
+	t.Cleanup(func() {
+		http.DefaultClient.Transport = transport
+		cfg, store, client, recorder, replayer = nil, nil, nil, nil, nil
+		recordPath, replayPath = "", ""
+	})
+	http.DefaultClient.Transport = srv.Client().Transport
+
+	cfg = &config.Config{}
+	cfg.Neynar.APIKey = neynartest.APIKey
+	cfg.Neynar.BaseUrl = srv.URL
+	cfg.DB.Dir = t.TempDir()
+	cfg.Signer.KeyFile = filepath.Join(t.TempDir(), "signer.key")
+	cassette := filepath.Join(t.TempDir(), "cassette.jsonl")
+	recordPath = cassette
+	signer := &api.Signer{FID: 1, UUID: neynartest.SignerUUID, Username: "alice", PublicKey: api.LocalPublicKey}
+	feed := &api.FeedRequest{FeedType: "following", FID: signer.FID, ViewerFID: signer.FID}
+	const hash = "0x0000000000000000000000000000000000000a01"
+
+	initCassette()
+	initStore()
+	client = api.NewClient(cfg, store)
+	k, err := api.LoadSignerKeyFile(cfg.Signer.KeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetSignerKey(k, api.AllSigners)
+	if err := client.SetSigner(signer); err != nil {
+		t.Fatal(err)
+	}
+	unlockSigners()
+	if _, err := client.GetFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.React(client.GetSigner(api.LocalPublicKey), hash, api.Like); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	store.Close()
+	closeCassette()
+
+	// a replay has no key file, and no terminal to prompt for a passphrase
+	srv.Close()
+	cfg.Signer.KeyFile = ""
+	recordPath, recorder = "", nil
+	replayPath = cassette
+	initCassette()
+	initStore()
+	client = api.NewClient(cfg, store)
+	unlockSigners()
+
+	got := client.GetSigner(api.LocalPublicKey)
+	if got == nil || got.FID != signer.FID || got.Username != signer.Username {
+		t.Fatalf("expected to replay signed in as %+v, got %+v", signer, got)
+	}
+	if _, err := client.GetFeed(feed); err != nil {
+		t.Errorf("expected replayed feed: %v", err)
+	}
+	if err := client.React(got, hash, api.Like); err != nil {
+		t.Errorf("expected replayed reaction: %v", err)
+	}
+}
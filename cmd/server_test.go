@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/treethought/tofui/api"
+	"github.com/treethought/tofui/api/neynartest"
+	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
+)
+
+func TestReadyz(t *testing.T) {
+	store = db.NewMemoryStore(&config.Config{})
+	t.Cleanup(func() { store = nil })
+	sv := NewServer()
+
+	check := func(want int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		sv.HttpHandleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != want {
+			t.Errorf("expected %d, got %d: %s", want, w.Code, w.Body)
+		}
+	}
+	check(http.StatusServiceUnavailable)
+	sv.ready.Store(true)
+	check(http.StatusOK)
+}
+
+func TestSigninSuccessKeepsNonceOnFailure(t *testing.T) {
+	srv := neynartest.NewServer()
+	t.Cleanup(srv.Close)
+	c := &config.Config{}
+	c.Neynar.APIKey = neynartest.APIKey
+	c.Neynar.BaseUrl = srv.URL
+	store = db.NewMemoryStore(c)
+	client = api.NewClient(c, store)
+	client.SetSignerKey(api.NewRandomSignerKey(), api.AllSigners)
+	t.Cleanup(func() { store, client = nil, nil })
+
+	sv := NewServer()
+	nonce, _ := sv.nonces.Issue("pk")
+	callback := func(uuid string) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		url := fmt.Sprintf("/signin/success?fid=1&signer_uuid=%s&nonce=%s", uuid, nonce)
+		sv.HttpHandleSigninSuccess(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w.Code
+	}
+
+	if code := callback(neynartest.RevokedSignerUUID); code != http.StatusForbidden {
+		t.Fatalf("expected revoked signer to be rejected, got %d", code)
+	}
+	if !sv.nonces.Valid(nonce) {
+		t.Fatal("expected a rejected callback to leave the nonce usable")
+	}
+	if code := callback(neynartest.SignerUUID); code != http.StatusOK {
+		t.Fatalf("expected sign in to succeed, got %d", code)
+	}
+	if s := client.GetSigner("pk"); s == nil || s.FID != 1 {
+		t.Errorf("expected signer for fid 1, got %+v", s)
+	}
+	if code := callback(neynartest.SignerUUID); code != http.StatusForbidden {
+		t.Errorf("expected replayed callback to be rejected, got %d", code)
+	}
+}
+
+func TestIndexShowsSSHCommand(t *testing.T) {
+	cfg = &config.Config{}
+	cfg.Server.Host = "tofui.example.com"
+	cfg.Server.SSHPort = 2222
+	t.Cleanup(func() { cfg = nil })
+	sv := NewServer()
+
+	w := httptest.NewRecorder()
+	sv.HttpHandleIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := "ssh -p 2222 you@tofui.example.com"; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected index to show %q, got %s", want, w.Body)
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/time/rate"
+
+	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/metrics"
+	"github.com/treethought/tofui/ui"
+)
+
+type session struct {
+	id      uint64
+	pk      string
+	remote  string
+	started time.Time
+	app     *ui.App
+	prgm    *tea.Program
+}
+
+// sessionRegistry tracks the running SSH sessions, from when they are
+// accepted until they disconnect.
+type sessionRegistry struct {
+	mu       sync.Mutex
+	nextID   uint64
+	sessions map[uint64]*session
+	// request limiters shared by each key's sessions
+	limiters map[string]*rate.Limiter
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{
+		sessions: make(map[uint64]*session),
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// open registers a session for pk, returning it along with the request
+// limiter for the key. It fails when the key already has too many sessions.
+func (r *sessionRegistry) open(pk, remote string, limits config.Limits) (*session, *rate.Limiter, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if limits.SessionsPerKey > 0 && r.countLocked(pk) >= limits.SessionsPerKey {
+		return nil, nil, false
+	}
+	r.nextID++
+	s := &session{id: r.nextID, pk: pk, remote: remote, started: time.Now()}
+	r.sessions[s.id] = s
+	metrics.SSHSessions.Inc()
+
+	if limits.RequestsPerMinute <= 0 {
+		return s, nil, true
+	}
+	l, ok := r.limiters[pk]
+	if !ok {
+		n := limits.RequestsPerMinute
+		l = rate.NewLimiter(rate.Every(time.Minute/time.Duration(n)), n)
+		r.limiters[pk] = l
+	}
+	return s, l, true
+}
+
+// attach records the program running for the session.
+func (r *sessionRegistry) attach(s *session, app *ui.App, p *tea.Program) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.app, s.prgm = app, p
+}
+
+func (r *sessionRegistry) close(s *session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[s.id]; !ok {
+		return
+	}
+	delete(r.sessions, s.id)
+	metrics.SSHSessions.Dec()
+	if r.countLocked(s.pk) == 0 {
+		delete(r.limiters, s.pk)
+	}
+}
+
+func (r *sessionRegistry) countLocked(pk string) int {
+	n := 0
+	for _, s := range r.sessions {
+		if s.pk == pk {
+			n++
+		}
+	}
+	return n
+}
+
+// list returns the sessions oldest first.
+func (r *sessionRegistry) list() []session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+	return list
+}
+
+// broadcast sends msg to every running program, returning how many it
+// reached.
+func (r *sessionRegistry) broadcast(msg tea.Msg) int {
+	r.mu.Lock()
+	prgms := make([]*tea.Program, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		if s.prgm != nil {
+			prgms = append(prgms, s.prgm)
+		}
+	}
+	r.mu.Unlock()
+	// Send blocks until the program reads the message
+	for _, p := range prgms {
+		go p.Send(msg)
+	}
+	return len(prgms)
+}
+
+// quitAll ends every running program.
+func (r *sessionRegistry) quitAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.sessions {
+		if s.prgm != nil {
+			s.prgm.Quit()
+		}
+	}
+}
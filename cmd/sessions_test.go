@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/treethought/tofui/config"
+)
+
+func TestSessionRegistry(t *testing.T) {
+	r := newSessionRegistry()
+	limits := config.Limits{SessionsPerKey: 2, RequestsPerMinute: 60}
+
+	a, la, ok := r.open("pk-a", "1.2.3.4:1", limits)
+	if !ok || la == nil {
+		t.Fatal("expected first session to open with a limiter")
+	}
+	b, lb, ok := r.open("pk-a", "1.2.3.4:2", limits)
+	if !ok || lb != la {
+		t.Fatal("expected sessions for a key to share a limiter")
+	}
+	if _, _, ok := r.open("pk-a", "1.2.3.4:3", limits); ok {
+		t.Error("expected third session for the key to be rejected")
+	}
+	if _, _, ok := r.open("pk-b", "5.6.7.8:1", limits); !ok {
+		t.Error("expected other keys to be unaffected")
+	}
+	if n := len(r.list()); n != 3 {
+		t.Fatalf("expected 3 sessions, got %d", n)
+	}
+
+	r.close(a)
+	if _, _, ok := r.open("pk-a", "1.2.3.4:4", limits); !ok {
+		t.Error("expected a closed session to free its slot")
+	}
+	r.close(b)
+	for _, s := range r.list() {
+		if s.id == a.id || s.id == b.id {
+			t.Errorf("expected session %d to be removed", s.id)
+		}
+	}
+}
@@ -5,97 +5,252 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"html"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
 	"strconv"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"text/template"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
-	wlog "github.com/charmbracelet/log"
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
 	"github.com/charmbracelet/wish/accesscontrol"
 	"github.com/charmbracelet/wish/activeterm"
 	"github.com/charmbracelet/wish/bubbletea"
-	"github.com/charmbracelet/wish/logging"
+	wishlog "github.com/charmbracelet/wish/logging"
 	"github.com/muesli/termenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 
 	"github.com/treethought/tofui/api"
-	"github.com/treethought/tofui/db"
+	"github.com/treethought/tofui/auth"
+	"github.com/treethought/tofui/logging"
+	"github.com/treethought/tofui/metrics"
 	"github.com/treethought/tofui/ui"
 )
 
 var (
 	//go:embed siwn.html
 	sinwhtml []byte
-	host     = "0.0.0.0"
-	port     = "42069"
+	//go:embed device.html
+	deviceForm []byte
+
+	sshListen         string
+	sshPort           int
+	sshHostKeys       []string
+	sshAuthorizedKeys string
+	sshMaxSessions    int
+)
+
+const (
+	defaultListen  = "0.0.0.0"
+	defaultSSHPort = 42069
+	defaultHostKey = ".ssh/tofui_ed25519"
 )
 
+// signinNonceTTL is how long a sign in link stays valid.
+const signinNonceTTL = 10 * time.Minute
+
+const defaultShutdownGrace = 30 * time.Second
+
 type Server struct {
-	// user may have more than one session
-	// map of pk to active programs
-	prgmSessions map[string][]*tea.Program
-	mux          sync.Mutex
+	mux      sync.Mutex
+	nonces   *auth.Nonces
+	sessions int
+	registry *sessionRegistry
+	// ready is set once the app is accepting sessions
+	ready atomic.Bool
+	// stopping is set once sessions are being drained
+	stopping atomic.Bool
 }
 
+func NewServer() *Server {
+	return &Server{
+		nonces:   auth.NewNonces(signinNonceTTL),
+		registry: newSessionRegistry(),
+	}
+}
+
+type appContextKey struct{}
+
 // sshCmd represents the ssh command
 var sshCmd = &cobra.Command{
 	Use:   "ssh",
 	Short: "serve tofui over ssh",
 	Run: func(cmd *cobra.Command, args []string) {
-		defer logFile.Close()
-		defer db.GetDB().Close()
-		sv := &Server{
-			prgmSessions: make(map[string][]*tea.Program),
-		}
-		go sv.startSigninHTTPServer()
-		sv.runSSHServer()
+		defer shutdown()
+		applySSHFlags(cmd)
+		unlockServerSigners()
+		sv := NewServer()
+		srv := sv.startSigninHTTPServer()
+		defer stopHTTPServer(srv)
+		sv.runSSHServer(cmd.Context())
 	},
 }
 
-func (sv *Server) runSSHServer() {
-	addr := host + ":" + port
-	s, err := wish.NewServer(
-		wish.WithAddress(addr),
-		wish.WithHostKeyPath(".ssh/tofui_ed25519"),
-		// Accept any public key.
-		ssh.PublicKeyAuth(func(ssh.Context, ssh.PublicKey) bool { return true }),
+// applySSHFlags overrides the server config with flags that were set, and
+// fills in defaults for anything left unset.
+func applySSHFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	if flags.Changed("listen") {
+		cfg.Server.Listen = sshListen
+	}
+	if flags.Changed("port") {
+		cfg.Server.SSHPort = sshPort
+	}
+	if flags.Changed("host-key") {
+		cfg.Server.HostKeys = sshHostKeys
+	}
+	if flags.Changed("authorized-keys") {
+		cfg.Server.AuthorizedKeys = sshAuthorizedKeys
+	}
+	if flags.Changed("max-sessions") {
+		cfg.Server.MaxSessions = sshMaxSessions
+	}
+
+	if cfg.Server.Listen == "" {
+		cfg.Server.Listen = defaultListen
+	}
+	if cfg.Server.SSHPort == 0 {
+		cfg.Server.SSHPort = defaultSSHPort
+	}
+	if len(cfg.Server.HostKeys) == 0 {
+		cfg.Server.HostKeys = []string{defaultHostKey}
+	}
+	if cfg.Server.ShutdownGrace == 0 {
+		cfg.Server.ShutdownGrace = defaultShutdownGrace
+	}
+}
+
+// runSSHServer serves sessions until ctx is cancelled, then drains them.
+func (sv *Server) runSSHServer(ctx context.Context) {
+	keys, err := auth.NewKeyPolicy(cfg.Server.AllowKeys, cfg.Server.DenyKeys, cfg.Server.AuthorizedKeys)
+	if err != nil {
+		log.Fatal("invalid ssh key policy: ", err)
+	}
+	admins, err := auth.ParseKeyList(cfg.Server.AdminKeys)
+	if err != nil {
+		log.Fatal("invalid admin key: ", err)
+	}
+	addr := net.JoinHostPort(cfg.Server.Listen, strconv.Itoa(cfg.Server.SSHPort))
+	opts := []ssh.Option{wish.WithAddress(addr)}
+	for _, k := range cfg.Server.HostKeys {
+		opts = append(opts, wish.WithHostKeyPath(k))
+	}
+	opts = append(opts,
+		ssh.PublicKeyAuth(func(_ ssh.Context, key ssh.PublicKey) bool {
+			return keys.Allowed(key)
+		}),
 		// Do not accept password auth.
 		ssh.PasswordAuth(func(ssh.Context, string) bool { return false }),
 		wish.WithMiddleware(
+			sv.goodbye(),
 			sv.teaMiddleware(),
 			activeterm.Middleware(),
-			logging.Middleware(),
+			wishlog.MiddlewareWithLogger(logging.PrintfLogger{Component: "ssh"}),
 			accesscontrol.Middleware(),
+			sv.limitSessions(cfg.Server.MaxSessions),
+			sv.adminMiddleware(admins),
 		),
 	)
+	s, err := wish.NewServer(opts...)
+	if err != nil {
+		log.Fatal("could not create ssh server: ", err)
+	}
+
+	slog.Info("starting ssh server", "addr", addr)
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
-		wlog.Error("Could not start server", "error", err)
+		log.Fatal("could not listen: ", err)
+	}
+	sv.ready.Store(true)
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(ln) }()
+
+	select {
+	case <-ctx.Done():
+	case err := <-served:
+		if !errors.Is(err, ssh.ErrServerClosed) {
+			slog.Error("ssh server stopped", "error", err)
+		}
+	}
+	sv.ready.Store(false)
+	sv.drain(s, cfg.Server.ShutdownGrace)
+}
+
+// drain stops accepting connections and gives connected sessions grace to
+// finish, after which their programs are ended.
+func (sv *Server) drain(s *ssh.Server, grace time.Duration) {
+	sv.stopping.Store(true)
+	n := sv.registry.broadcast(ui.BroadcastMsg{
+		Text: fmt.Sprintf("tofui is restarting, you will be disconnected in %s", grace),
+	})
+	slog.Info("stopping ssh server", "sessions", n, "grace", grace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := s.Shutdown(ctx); err == nil {
+		return
+	}
+	slog.Warn("grace period over, ending sessions", "sessions", len(sv.registry.list()))
+	sv.registry.quitAll()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		slog.Error("could not stop ssh server", "error", err)
+		s.Close()
 	}
+}
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	wlog.Info("Starting SSH server", "host", host, "port", port)
-	go func() {
-		if err = s.ListenAndServe(); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
-			wlog.Error("Could not start server", "error", err)
-			done <- nil
+// limitSessions turns away new sessions once max are connected.
+func (sv *Server) limitSessions(max int) wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(s ssh.Session) {
+			if max > 0 {
+				sv.mux.Lock()
+				full := sv.sessions >= max
+				if !full {
+					sv.sessions++
+				}
+				sv.mux.Unlock()
+				if full {
+					slog.Warn("rejecting session, server is full", "max", max)
+					metrics.SSHSessionsRejected.WithLabelValues("server_full").Inc()
+					wish.Fatalln(s, "tofui is at capacity, please try again later")
+					return
+				}
+				defer func() {
+					sv.mux.Lock()
+					sv.sessions--
+					sv.mux.Unlock()
+				}()
+			}
+			next(s)
 		}
-	}()
+	}
+}
 
-	<-done
-	wlog.Info("Stopping SSH server")
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer func() { cancel() }()
-	if err := s.Shutdown(ctx); err != nil && !errors.Is(err, ssh.ErrServerClosed) {
-		wlog.Error("Could not stop server", "error", err)
+// goodbye tells users why their session ended when it timed out or the
+// server is stopping. It runs after the program exits.
+func (sv *Server) goodbye() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(s ssh.Session) {
+			app, ok := s.Context().Value(appContextKey{}).(*ui.App)
+			switch {
+			case !ok:
+			case app.TimedOut():
+				wish.Printf(s, "Disconnected after %s without input, come back anytime!\n", cfg.Server.Limits.IdleTimeout)
+			case sv.stopping.Load():
+				wish.Println(s, "tofui is restarting, come back in a minute!")
+			}
+			next(s)
+		}
 	}
 }
 
@@ -106,23 +261,37 @@ func (sv *Server) teaMiddleware() wish.Middleware {
 			wish.Fatalln(s, "no active terminal, skipping")
 			return nil
 		}
+		if s.PublicKey() == nil {
+			wish.Fatalln(s, "public key required")
+			return nil
+		}
+		pk := auth.KeyHash(s.PublicKey())
+		sess, limiter, ok := sv.registry.open(pk, s.RemoteAddr().String(), cfg.Server.Limits)
+		if !ok {
+			slog.Warn("rejecting session, too many for key", "pk", pk)
+			metrics.SSHSessionsRejected.WithLabelValues("key_limit").Inc()
+			wish.Fatalln(s, "you have too many tofui sessions open, close one and try again")
+			return nil
+		}
 
 		renderer := bubbletea.MakeRenderer(s)
-		app, err := ui.NewSSHApp(cfg, s, renderer)
+		app, err := ui.NewSSHApp(cfg, client.WithLimiter(limiter), sv.nonces, s, renderer)
 		if err != nil {
-			wlog.Error("failed to create app", "error", err)
+			slog.Error("failed to create app", "pk", pk, "error", err)
+			sv.registry.close(sess)
 			return nil
 		}
-		if app.PublicKey() == "" {
-			log.Fatal("new app's public key is nil")
-		}
+		s.Context().SetValue(appContextKey{}, app)
 
 		p := tea.NewProgram(app, append(bubbletea.MakeOptions(s), tea.WithAltScreen())...)
 
-		sv.mux.Lock()
-		sv.prgmSessions[app.PublicKey()] = append(sv.prgmSessions[app.PublicKey()], p)
-		sv.mux.Unlock()
-		log.Println("new app session added: ", app.PublicKey())
+		sv.registry.attach(sess, app, p)
+		go func() {
+			<-s.Context().Done()
+			sv.registry.close(sess)
+			slog.Info("session closed", "pk", pk, "session", sess.id)
+		}()
+		slog.Info("session started", "pk", pk, "session", sess.id, "remote", sess.remote)
 		return p
 	}
 	return bubbletea.MiddlewareWithProgramHandler(teaHandler, termenv.ANSI256)
@@ -134,78 +303,144 @@ func (sv *Server) HttpHandleSignin(w http.ResponseWriter, r *http.Request) {
 		log.Fatal("failed to parse template: ", err)
 	}
 	data := struct {
-		ClientID  string
-		PublicKey string
-		BaseUrl   string
+		ClientID string
+		Nonce    string
+		BaseUrl  string
 	}{
 		ClientID: cfg.Neynar.ClientID,
 		BaseUrl:  cfg.BaseURL(),
 	}
-	query := r.URL.Query()
-	pk := query.Get("pk")
-	if pk == "" {
-		w.Write([]byte("error: missing pk"))
-		w.WriteHeader(http.StatusBadRequest)
+	nonce := r.URL.Query().Get("nonce")
+	if !sv.nonces.Valid(nonce) {
+		http.Error(w, "sign in link is invalid or expired, return to your terminal and try again", http.StatusBadRequest)
 		return
 	}
-	data.PublicKey = pk
+	data.Nonce = nonce
 	err = tmpl.Execute(w, data)
 	if err != nil {
-		log.Println("failed to execute template: ", err)
+		slog.Error("failed to execute template", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-func (sv *Server) HttpHandleSigninSuccess(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query()
-	pk := query.Get("pk")
-	if pk == "" {
-		w.Write([]byte("error: missing pk"))
-		w.WriteHeader(http.StatusBadRequest)
+// HttpHandleDevice exchanges the device code shown in the terminal for the
+// sign in page.
+func (sv *Server) HttpHandleDevice(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(deviceForm)
 		return
 	}
+	nonce, ok := sv.nonces.Lookup(code)
+	if !ok {
+		http.Error(w, "unknown or expired code, return to your terminal and try again", http.StatusNotFound)
+		return
+	}
+	http.Redirect(w, r, "/signin?nonce="+nonce, http.StatusFound)
+}
+
+func (sv *Server) HttpHandleSigninSuccess(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
 	fid, err := strconv.ParseUint(query.Get("fid"), 10, 64)
 	if err != nil {
-		w.Write([]byte("error: missing fid"))
-		w.WriteHeader(http.StatusBadRequest)
+		http.Error(w, "error: missing fid", http.StatusBadRequest)
 		return
 	}
 	signerUUid := query.Get("signer_uuid")
+	if signerUUid == "" {
+		http.Error(w, "error: missing signer_uuid", http.StatusBadRequest)
+		return
+	}
+	invalidNonce := func() {
+		metrics.Signins.WithLabelValues("invalid_nonce").Inc()
+		http.Error(w, "sign in link is invalid or expired, return to your terminal and try again", http.StatusForbidden)
+	}
+	nonce := query.Get("nonce")
+	if !sv.nonces.Valid(nonce) {
+		invalidNonce()
+		return
+	}
+	// the nonce is only used up once the signer checks out, so a bad
+	// callback doesn't cancel the user's sign in
+	if err := verifySigner(signerUUid, fid); err != nil {
+		slog.Error("rejected sign in", "error", err)
+		metrics.Signins.WithLabelValues("unverified").Inc()
+		http.Error(w, "sign in could not be verified, return to your terminal and try again", http.StatusForbidden)
+		return
+	}
+	// consumed here, so a replayed callback is rejected
+	pk, ok := sv.nonces.Consume(nonce)
+	if !ok {
+		invalidNonce()
+		return
+	}
 
-	sv.signinCallback(fid, signerUUid, pk)
+	sv.signinCallback(fid, signerUUid, pk, nonce)
 	w.Write([]byte("success, you may now close the window and return to your terminal."))
 }
 
-func (sv *Server) signinCallback(fid uint64, uuid, pk string) {
-	client := api.NewClient(cfg)
+// verifySigner checks with Neynar that the signer is approved and belongs
+// to fid.
+func verifySigner(uuid string, fid uint64) error {
+	status, err := client.LookupSigner(uuid)
+	if err != nil {
+		return err
+	}
+	if status.Status != "approved" {
+		return fmt.Errorf("signer is %s", status.Status)
+	}
+	if status.FID != fid {
+		return fmt.Errorf("signer belongs to fid %d, not %d", status.FID, fid)
+	}
+	return nil
+}
+
+func (sv *Server) signinCallback(fid uint64, uuid, pk, nonce string) {
 	signer := &api.Signer{FID: fid, UUID: uuid, PublicKey: pk}
 	if user, err := client.GetUserByFID(fid, fid); err == nil {
 		signer.Username = user.Username
 		signer.DisplayName = user.DisplayName
 	}
-	api.SetSigner(signer)
+	if err := client.SetSigner(signer); err != nil {
+		slog.Error("failed to save signer", "error", err)
+		metrics.Signins.WithLabelValues("error").Inc()
+		return
+	}
+	metrics.Signins.WithLabelValues("success").Inc()
+	// the session that issued the nonce polls for this
+	sv.nonces.Complete(nonce, fid)
+	fmt.Println("signed in as:", signer.Username)
+}
+
+func (sv *Server) HttpHandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
 
-	var prgms []*tea.Program
-	var ok bool
-	sv.mux.Lock()
-	prgms, ok = sv.prgmSessions[pk]
-	sv.mux.Unlock()
-	if !ok || len(prgms) == 0 {
-		log.Println("failed to send signin msg, session not found")
+// HttpHandleReadyz reports ready once sessions are being accepted and the
+// cache can be read.
+func (sv *Server) HttpHandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !sv.ready.Load() {
+		http.Error(w, "not accepting sessions", http.StatusServiceUnavailable)
 		return
 	}
-	for _, p := range prgms {
-		if p == nil {
-			log.Println("nil program")
-			continue
-		}
-		p.Send(&ui.UpdateSignerMsg{Signer: signer})
+	if err := store.Ping(); err != nil {
+		http.Error(w, "cache unavailable: "+err.Error(), http.StatusServiceUnavailable)
+		return
 	}
-	fmt.Println("signed in as:", signer.Username)
+	w.Write([]byte("ok"))
+}
+
+// sshCommand is how users connect to this server.
+func sshCommand() string {
+	if cfg.Server.SSHPort == 22 {
+		return fmt.Sprintf("ssh you@%s", cfg.Server.Host)
+	}
+	return fmt.Sprintf("ssh -p %d you@%s", cfg.Server.SSHPort, cfg.Server.Host)
 }
 
 func (sv *Server) HttpHandleIndex(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte(`
+	fmt.Fprintf(w, `
     <html>
       <head>
         <title>tofui</title>
@@ -214,55 +449,71 @@ func (sv *Server) HttpHandleIndex(w http.ResponseWriter, r *http.Request) {
         <h1>tofui</h1>
         <p>Terminally On Farcaster User Interface</p>
         <div>
-          <code>ssh -p 42069 you@tofui.xyz</code>
+          <code>%s</code>
           <br>
           <hr/
     <p>Or, visit <a href="https://github.com/treethought/tofui">the repo</a> for more info</p>
     </div>
     </body>
     </html>
-    `))
+    `, html.EscapeString(sshCommand()))
 }
 
-func (sv *Server) startSigninHTTPServer() {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// startSigninHTTPServer serves sign in, health and metrics in the
+// background. Stop it with stopHTTPServer.
+func (sv *Server) startSigninHTTPServer() *http.Server {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", sv.HttpHandleIndex)
 	mux.HandleFunc("/signin", sv.HttpHandleSignin)
+	mux.HandleFunc("/device", sv.HttpHandleDevice)
 	mux.HandleFunc("/signin/success", sv.HttpHandleSigninSuccess)
+	mux.HandleFunc("/healthz", sv.HttpHandleHealthz)
+	mux.HandleFunc("/readyz", sv.HttpHandleReadyz)
+	mux.Handle("/metrics", promhttp.Handler())
 
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.Server.HTTPPort),
+		Addr:    net.JoinHostPort(cfg.Server.Listen, strconv.Itoa(cfg.Server.HTTPPort)),
 		Handler: mux,
 	}
 
 	if cfg.Server.HTTPPort == 443 {
 		cert := fmt.Sprintf("%s/%s", cfg.Server.CertsDir, "cert.pem")
 		key := fmt.Sprintf("%s/%s", cfg.Server.CertsDir, "privkey.pem")
-		log.Println("serving TLS on ", srv.Addr)
+		slog.Info("serving https", "addr", srv.Addr)
 		go func() {
-			if err := srv.ListenAndServeTLS(cert, key); err != nil {
-				log.Println(err)
+			if err := srv.ListenAndServeTLS(cert, key); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("http server stopped", "error", err)
 			}
 		}()
 
 	} else {
-		log.Println("listening on ", srv.Addr)
+		slog.Info("serving http", "addr", srv.Addr)
 		go func() {
-			if err := srv.ListenAndServe(); err != nil {
-				log.Println(err)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("http server stopped", "error", err)
 			}
 		}()
 
 	}
+	return srv
+}
 
-	<-ctx.Done()
-	srv.Shutdown(context.Background())
-
+// stopHTTPServer lets in-flight requests, such as a sign in callback,
+// finish before closing the server.
+func stopHTTPServer(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("could not stop http server", "error", err)
+		srv.Close()
+	}
 }
 
 func init() {
+	sshCmd.Flags().StringVar(&sshListen, "listen", defaultListen, "address to listen on")
+	sshCmd.Flags().IntVarP(&sshPort, "port", "p", defaultSSHPort, "ssh port to listen on")
+	sshCmd.Flags().StringSliceVar(&sshHostKeys, "host-key", []string{defaultHostKey}, "host key files, created if missing")
+	sshCmd.Flags().StringVar(&sshAuthorizedKeys, "authorized-keys", "", "only accept keys listed in this authorized_keys file")
+	sshCmd.Flags().IntVar(&sshMaxSessions, "max-sessions", 0, "maximum concurrent sessions, 0 for no limit")
 	rootCmd.AddCommand(sshCmd)
-
 }
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/charmbracelet/x/term"
+
+	"github.com/treethought/tofui/api"
+)
+
+// serverSignerKeyFile is used to encrypt signers in SSH mode when no key
+// file is configured.
+const serverSignerKeyFile = ".ssh/tofui_signer.key"
+
+// unlockSigners provides the key used to encrypt signers at rest. A
+// configured key file takes precedence, otherwise the user is prompted for
+// their passphrase. Replays start signed in as the cassette was recorded.
+func unlockSigners() {
+	defer recordSigner()
+	if replayer != nil {
+		restoreSigner()
+		return
+	}
+	if noCache {
+		client.SetSignerKey(api.NewRandomSignerKey(), api.LocalSigners)
+		return
+	}
+	if cfg.Signer.KeyFile != "" {
+		// SSH mode uses the configured key file too
+		unlockWithKeyFile(cfg.Signer.KeyFile, api.AllSigners)
+		return
+	}
+	k, err := promptPassphrase()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	client.SetSignerKey(k, api.LocalSigners)
+}
+
+// unlockServerSigners unlocks signers for SSH mode, where there is no one to
+// prompt so a key file is always used.
+func unlockServerSigners() {
+	if cfg.Signer.KeyFile != "" {
+		unlockWithKeyFile(cfg.Signer.KeyFile, api.AllSigners)
+		return
+	}
+	unlockWithKeyFile(serverSignerKeyFile, api.SSHSigners)
+}
+
+// recordSigner saves the account in use to the cassette being recorded.
+func recordSigner() {
+	if recorder == nil {
+		return
+	}
+	s := client.GetSigner(api.LocalPublicKey)
+	if s == nil {
+		return
+	}
+	if err := recorder.RecordSigner(s); err != nil {
+		slog.Error("failed to record signer", "error", err)
+	}
+}
+
+// restoreSigner signs in as the account the cassette was recorded with,
+// in the in-memory store replays use.
+func restoreSigner() {
+	client.SetSignerKey(api.NewRandomSignerKey(), api.LocalSigners)
+	s := replayer.Signer()
+	if s == nil {
+		return
+	}
+	if err := client.SetSigner(s); err != nil {
+		slog.Error("failed to restore recorded signer", "error", err)
+	}
+}
+
+func unlockWithKeyFile(path string, scope api.SignerScope) {
+	k, err := api.LoadSignerKeyFile(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	client.SetSignerKey(k, scope)
+}
+
+func promptPassphrase() (*api.SignerKey, error) {
+	fd := os.Stdin.Fd()
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("no terminal to read the passphrase from, set signer.key_file in your config instead")
+	}
+	read := func(prompt string) ([]byte, error) {
+		fmt.Print(prompt)
+		p, err := term.ReadPassword(fd)
+		fmt.Println()
+		return p, err
+	}
+
+	if !api.HasPassphrase(store) {
+		fmt.Println("Choose a passphrase to encrypt your sign-in")
+		p, err := read("passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if len(p) == 0 {
+			return nil, errors.New("passphrase can not be empty")
+		}
+		confirm, err := read("confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(p, confirm) {
+			return nil, errors.New("passphrases do not match")
+		}
+		return api.UnlockWithPassphrase(store, p)
+	}
+
+	for i := 0; i < 3; i++ {
+		p, err := read("passphrase to unlock tofui: ")
+		if err != nil {
+			return nil, err
+		}
+		k, err := api.UnlockWithPassphrase(store, p)
+		if errors.Is(err, api.ErrBadPassphrase) {
+			fmt.Println(err)
+			continue
+		}
+		return k, err
+	}
+	return nil, api.ErrBadPassphrase
+}
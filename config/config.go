@@ -1,8 +1,14 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,23 +16,125 @@ import (
 type Config struct {
 	Log struct {
 		Path string `yaml:"path"`
+		// Level is one of debug, info, warn or error, info by default
+		Level string `yaml:"level,omitempty"`
+		// Format is text or json, text by default
+		Format string `yaml:"format,omitempty"`
+		// the log file is rotated once it reaches MaxSizeMB, 50 by default.
+		// MaxBackups and MaxAgeDays limit the rotated files kept, 0 keeps all
+		MaxSizeMB  int `yaml:"max_size_mb,omitempty"`
+		MaxBackups int `yaml:"max_backups,omitempty"`
+		MaxAgeDays int `yaml:"max_age_days,omitempty"`
 	} `yaml:"log"`
 	DB struct {
 		Dir string `yaml:"dir"`
 	}
 	Server struct {
+		// Host is the public hostname used in sign in links
 		Host     string `yaml:"host"`
 		SSHPort  int    `yaml:"ssh_port"`
 		HTTPPort int    `yaml:"http_port"`
 		CertsDir string `yaml:"certs_dir"`
+		// Listen is the address the servers bind to, all interfaces by default
+		Listen   string   `yaml:"listen,omitempty"`
+		HostKeys []string `yaml:"host_keys,omitempty"`
+		// AllowKeys and DenyKeys hold public keys in authorized_keys format.
+		// When AllowKeys or AuthorizedKeys is set, only keys listed there may
+		// connect.
+		AllowKeys      []string `yaml:"allow_keys,omitempty"`
+		DenyKeys       []string `yaml:"deny_keys,omitempty"`
+		AuthorizedKeys string   `yaml:"authorized_keys,omitempty"`
+		// AdminKeys may run admin commands such as `ssh <host> sessions`
+		AdminKeys []string `yaml:"admin_keys,omitempty"`
+		// MaxSessions caps concurrent SSH sessions, 0 means no limit
+		MaxSessions int    `yaml:"max_sessions,omitempty"`
+		Limits      Limits `yaml:"limits,omitempty"`
+		// ShutdownGrace is how long connected sessions are given to finish
+		// when the server is stopping, 30s by default
+		ShutdownGrace time.Duration `yaml:"shutdown_grace,omitempty"`
 	}
 	Neynar struct {
 		APIKey   string `yaml:"api_key"`
 		ClientID string `yaml:"client_id"`
 		BaseUrl  string `yaml:"base_url"`
 	}
+	Signer struct {
+		// KeyFile holds a 32 byte key used to encrypt signers instead of a
+		// passphrase. It is created if missing.
+		KeyFile string `yaml:"key_file"`
+	} `yaml:"signer"`
+	Cache struct {
+		User    CachePolicy `yaml:"user"`
+		Channel CachePolicy `yaml:"channel"`
+		Embed   CachePolicy `yaml:"embed"`
+		Image   CachePolicy `yaml:"image"`
+	} `yaml:"cache"`
+	// Keys remaps actions by name, such as nav.quick_select: [ctrl+p]
+	Keys   map[string][]string `yaml:"keys,omitempty"`
+	Theme  Theme               `yaml:"theme,omitempty"`
+	Images struct {
+		// Protocol is how images are drawn: auto, kitty, iterm2, sixel or
+		// halfblocks. auto picks from the terminal, halfblocks when unsure.
+		Protocol string `yaml:"protocol,omitempty"`
+		// VideoPlayer is the command video embeds are played with, mpv by
+		// default
+		VideoPlayer string `yaml:"video_player,omitempty"`
+	} `yaml:"images,omitempty"`
 }
 
+// ImageProtocols are the values images.protocol accepts.
+var ImageProtocols = []string{"auto", "kitty", "iterm2", "sixel", "halfblocks"}
+
+// Theme picks the palette tofui starts with.
+type Theme struct {
+	// Name is auto, dark, light or high-contrast, auto by default
+	Name string `yaml:"name,omitempty"`
+	// Colors override the named theme's palette, making a custom theme
+	Colors ThemeColors `yaml:"colors,omitempty"`
+}
+
+// ThemeColors are hex colors such as "#7D56F4" or ANSI 256 color numbers.
+type ThemeColors struct {
+	Accent       string `yaml:"accent,omitempty"`
+	Subtle       string `yaml:"subtle,omitempty"`
+	Special      string `yaml:"special,omitempty"`
+	Error        string `yaml:"error,omitempty"`
+	StatusFg     string `yaml:"status_fg,omitempty"`
+	StatusBg     string `yaml:"status_bg,omitempty"`
+	HeaderBorder string `yaml:"header_border,omitempty"`
+	SelectedFg   string `yaml:"selected_fg,omitempty"`
+	SelectedBg   string `yaml:"selected_bg,omitempty"`
+	// Markdown is the glamour style for cast text: auto, dark, light,
+	// dracula, pink or ascii
+	Markdown string `yaml:"markdown,omitempty"`
+}
+
+// Limits bound the resources a single SSH user can use. Zero values mean no
+// limit, except ImageFetches which applies to every session.
+type Limits struct {
+	// SessionsPerKey caps concurrent sessions for one public key
+	SessionsPerKey int `yaml:"sessions_per_key,omitempty"`
+	// RequestsPerMinute caps Neynar API requests across a key's sessions
+	RequestsPerMinute int `yaml:"requests_per_minute,omitempty"`
+	// IdleTimeout ends sessions that have had no input for this long
+	IdleTimeout time.Duration `yaml:"idle_timeout,omitempty"`
+	// ImageFetches caps concurrent image downloads in a session
+	ImageFetches int `yaml:"image_fetches,omitempty"`
+}
+
+// CachePolicy controls how long cached entries are considered fresh, and
+// how long after that they may still be served while being refreshed.
+// MaxSizeMB caps the namespace on disk, evicting least recently used entries.
+// A TTL of 0 keeps entries fresh forever, unset keeps the default.
+type CachePolicy struct {
+	TTL       *time.Duration `yaml:"ttl,omitempty"`
+	Stale     time.Duration  `yaml:"stale"`
+	MaxSizeMB int64          `yaml:"max_size_mb,omitempty"`
+}
+
+// DefaultBaseURL is Neynar's v2 farcaster API.
+const DefaultBaseURL = "https://api.neynar.com/v2/farcaster"
+
 func ReadConfig(path string) (*Config, error) {
 	var c Config
 	data, err := os.ReadFile(path)
@@ -39,6 +147,94 @@ func ReadConfig(path string) (*Config, error) {
 	return &c, nil
 }
 
+// Load reads the config file at path, if there is one, applies TOFUI_*
+// environment overrides and fills in defaults. It does not validate.
+func Load(path string) (*Config, error) {
+	c, err := ReadConfig(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c = &Config{}
+	} else if err != nil {
+		return nil, err
+	}
+	if err := c.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	c.applyDefaults()
+	return c, nil
+}
+
+func (c *Config) applyDefaults() {
+	if c.Neynar.BaseUrl == "" {
+		c.Neynar.BaseUrl = DefaultBaseURL
+	}
+	if c.DB.Dir == "" {
+		c.DB.Dir = filepath.Join(DataDir(), "db")
+	}
+	if c.Log.Path == "" {
+		c.Log.Path = filepath.Join(CacheDir(), "tofui.log")
+	}
+}
+
+// Validate reports every problem with the config at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Neynar.APIKey == "" {
+		fail("neynar.api_key is required, set it in the config file or TOFUI_NEYNAR_API_KEY")
+	}
+	if u, err := url.Parse(c.Neynar.BaseUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("neynar.base_url %q is not an http(s) URL", c.Neynar.BaseUrl)
+	}
+	if p := c.Server.SSHPort; p < 0 || p > 65535 {
+		fail("server.ssh_port %d is not a valid port", p)
+	}
+	if p := c.Server.HTTPPort; p < 0 || p > 65535 {
+		fail("server.http_port %d is not a valid port", p)
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		fail("log.level %q must be debug, info, warn or error", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "", "text", "json":
+	default:
+		fail("log.format %q must be text or json", c.Log.Format)
+	}
+	if c.Server.MaxSessions < 0 {
+		fail("server.max_sessions must not be negative")
+	}
+	if c.Server.ShutdownGrace < 0 {
+		fail("server.shutdown_grace must not be negative")
+	}
+	if p := c.Images.Protocol; p != "" && !slices.Contains(ImageProtocols, p) {
+		fail("images.protocol %q must be one of %s", p, strings.Join(ImageProtocols, ", "))
+	}
+	l := c.Server.Limits
+	if l.SessionsPerKey < 0 || l.RequestsPerMinute < 0 || l.ImageFetches < 0 || l.IdleTimeout < 0 {
+		fail("server.limits must not be negative")
+	}
+	return errors.Join(errs...)
+}
+
+// Masked returns a copy of the config that is safe to print, with secrets
+// reduced to their last four characters.
+func (c *Config) Masked() *Config {
+	m := *c
+	m.Neynar.APIKey = mask(c.Neynar.APIKey)
+	return &m
+}
+
+func mask(s string) string {
+	if len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
+
 func (c *Config) BaseURL() string {
 	if c.Server.HTTPPort == 443 {
 		return "https://" + c.Server.Host
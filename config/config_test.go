@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadEnvOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("neynar:\n  api_key: from-file\nserver:\n  ssh_port: 22\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOFUI_NEYNAR_API_KEY", "from-env")
+	t.Setenv("TOFUI_SERVER_LIMITS_IDLE_TIMEOUT", "15m")
+	t.Setenv("TOFUI_SERVER_ALLOW_KEYS", "ssh-ed25519 AAAA a, ssh-ed25519 BBBB b")
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Neynar.APIKey != "from-env" {
+		t.Errorf("expected env to override the file, got %q", c.Neynar.APIKey)
+	}
+	if c.Server.SSHPort != 22 {
+		t.Errorf("expected file value to be kept, got %d", c.Server.SSHPort)
+	}
+	if c.Server.Limits.IdleTimeout != 15*time.Minute {
+		t.Errorf("expected idle timeout of 15m, got %s", c.Server.Limits.IdleTimeout)
+	}
+	if len(c.Server.AllowKeys) != 2 || c.Server.AllowKeys[1] != "ssh-ed25519 BBBB b" {
+		t.Errorf("unexpected allow keys %q", c.Server.AllowKeys)
+	}
+	if c.DB.Dir != "/xdg/data/tofui/db" {
+		t.Errorf("expected db in the XDG data dir, got %s", c.DB.Dir)
+	}
+	if c.Neynar.BaseUrl != DefaultBaseURL {
+		t.Errorf("expected default base url, got %s", c.Neynar.BaseUrl)
+	}
+
+	t.Setenv("TOFUI_SERVER_HTTP_PORT", "http")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "TOFUI_SERVER_HTTP_PORT") {
+		t.Errorf("expected error naming the variable, got %v", err)
+	}
+}
+
+func TestLoadCacheTTLEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("cache:\n  user:\n    ttl: 5m\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOFUI_CACHE_USER_TTL", "1h")
+	t.Setenv("TOFUI_CACHE_CHANNEL_TTL", "0")
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Cache.User.TTL == nil || *c.Cache.User.TTL != time.Hour {
+		t.Errorf("expected user ttl of 1h, got %v", c.Cache.User.TTL)
+	}
+	if c.Cache.Channel.TTL == nil || *c.Cache.Channel.TTL != 0 {
+		t.Errorf("expected channel ttl of 0 to be set, got %v", c.Cache.Channel.TTL)
+	}
+	if c.Cache.Embed.TTL != nil {
+		t.Errorf("expected embed ttl to stay unset, got %v", *c.Cache.Embed.TTL)
+	}
+
+	t.Setenv("TOFUI_CACHE_USER_TTL", "soon")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "TOFUI_CACHE_USER_TTL") {
+		t.Errorf("expected error naming the variable, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := &Config{}
+	c.Neynar.BaseUrl = "api.neynar.com"
+	c.Log.Level = "loud"
+	c.Images.Protocol = "ascii-art"
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected invalid config")
+	}
+	for _, want := range []string{"neynar.api_key", "neynar.base_url", "log.level", "images.protocol"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got %v", want, err)
+		}
+	}
+
+	c.Neynar.APIKey = "key"
+	c.Neynar.BaseUrl = DefaultBaseURL
+	c.Log.Level = "debug"
+	c.Images.Protocol = "sixel"
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+}
+
+func TestMasked(t *testing.T) {
+	c := &Config{}
+	c.Neynar.APIKey = "NEYNAR_SECRET_1234"
+	m := c.Masked()
+	if strings.Contains(m.Neynar.APIKey, "SECRET") || !strings.HasSuffix(m.Neynar.APIKey, "1234") {
+		t.Errorf("expected key to be masked, got %s", m.Neynar.APIKey)
+	}
+	if c.Neynar.APIKey != "NEYNAR_SECRET_1234" {
+		t.Error("expected original to be unchanged")
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts the environment variables that override config fields.
+// A field's variable is its yaml path in upper case joined by underscores,
+// so neynar.api_key is TOFUI_NEYNAR_API_KEY. Lists are comma separated, and
+// keys takes action=key pairs separated by semicolons, with spaces between
+// the keys for one action, as in TOFUI_KEYS="nav.help=? f1;publish.cast=ctrl+s".
+const EnvPrefix = "TOFUI_"
+
+// ApplyEnv overrides fields with any TOFUI_* variables that are set.
+func (c *Config) ApplyEnv() error {
+	return applyEnv(reflect.ValueOf(c).Elem(), strings.TrimSuffix(EnvPrefix, "_"))
+}
+
+// EnvVars lists every variable that ApplyEnv reads.
+func EnvVars() []string {
+	var vars []string
+	walkFields(reflect.TypeOf(Config{}), strings.TrimSuffix(EnvPrefix, "_"), func(name string) {
+		vars = append(vars, name)
+	})
+	return vars
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		name := prefix + "_" + strings.ToUpper(fieldName(t.Field(i)))
+		if f.Kind() == reflect.Struct {
+			if err := applyEnv(f, name); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setField(f, s); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func walkFields(t reflect.Type, prefix string, fn func(string)) {
+	for i := 0; i < t.NumField(); i++ {
+		name := prefix + "_" + strings.ToUpper(fieldName(t.Field(i)))
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			walkFields(t.Field(i).Type, name, fn)
+			continue
+		}
+		fn(name)
+	}
+}
+
+// fieldName is the field's yaml key, which yaml.v3 defaults to the
+// lowercased field name.
+func fieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+	return name
+}
+
+func setField(f reflect.Value, s string) error {
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.Ptr:
+		// a set variable always sets the field, even to the zero value
+		v := reflect.New(f.Type().Elem())
+		if err := setField(v.Elem(), s); err != nil {
+			return err
+		}
+		f.Set(v)
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		m := map[string][]string{}
+		for _, pair := range strings.Split(s, ";") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("expected action=keys, got %q", pair)
+			}
+			m[strings.TrimSpace(k)] = strings.Fields(v)
+		}
+		f.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const appName = "tofui"
+
+// ConfigDir is $XDG_CONFIG_HOME/tofui, or ~/.config/tofui.
+func ConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir is $XDG_DATA_HOME/tofui, or ~/.local/share/tofui. It holds the db.
+func DataDir() string {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// CacheDir is $XDG_CACHE_HOME/tofui, or ~/.cache/tofui. It holds the log.
+func CacheDir() string {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appName)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "." + appName
+	}
+	return filepath.Join(home, fallback, appName)
+}
+
+// legacyDir is where config, db and log were kept before XDG dirs were used.
+func legacyDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, "."+appName)
+}
+
+// DefaultPath returns the config file to use when none is given:
+// $TOFUI_CONFIG, ./config.yaml, the XDG config dir, then ~/.tofui. If none
+// exist the XDG path is returned, which is where `tofui init` writes.
+func DefaultPath() string {
+	if p := os.Getenv("TOFUI_CONFIG"); p != "" {
+		return p
+	}
+	xdg := filepath.Join(ConfigDir(), "config.yaml")
+	candidates := []string{"config.yaml", xdg}
+	if dir := legacyDir(); dir != "" {
+		candidates = append(candidates, filepath.Join(dir, "config.yaml"))
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return xdg
+}
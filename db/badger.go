@@ -0,0 +1,243 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+
+	"github.com/treethought/tofui/config"
+)
+
+// BadgerStore is a Store persisted to disk at the configured db dir.
+type BadgerStore struct {
+	*policySet
+	usage *usage
+	db    *badger.DB
+	done  chan struct{}
+}
+
+func OpenBadger(cfg *config.Config) (*BadgerStore, error) {
+	path := cfg.DB.Dir
+	if path == "" {
+		path = ".tofui/db"
+	}
+
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	slog.Info("opening db", "path", path)
+	opts := badger.DefaultOptions(path)
+	opts.Logger = badgerLogger{}
+
+	b, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %w", err)
+	}
+	s := &BadgerStore{
+		policySet: newPolicySet(cfg),
+		usage:     newUsage(),
+		db:        b,
+		done:      make(chan struct{}),
+	}
+	if err := s.migrate(); err != nil {
+		s.db.Close()
+		return nil, err
+	}
+	go s.runGC()
+	return s, nil
+}
+
+func (s *BadgerStore) runGC() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+		s.EnforceLimits()
+		s.Reclaim()
+	}
+}
+
+// EnforceLimits evicts the least recently used entries from prefixes that
+// have grown past their size cap.
+func (s *BadgerStore) EnforceLimits() {
+	for prefix, max := range s.limits() {
+		if _, err := evictLRU(s, s.usage, prefix, max); err != nil {
+			slog.Error("failed to evict", "prefix", prefix, "error", err)
+		}
+	}
+}
+
+// Reclaim runs value log garbage collection so space from deleted and
+// expired entries is returned to disk.
+func (s *BadgerStore) Reclaim() {
+	for {
+		if err := s.db.RunValueLogGC(0.7); err != nil {
+			return
+		}
+	}
+}
+
+// Ping reads the schema version, which is stored outside the envelope so
+// reading it through Get would drop it.
+func (s *BadgerStore) Ping() error {
+	_, err := s.schema()
+	return err
+}
+
+func (s *BadgerStore) Close() {
+	slog.Info("closing db")
+	if s != nil && s.db != nil {
+		close(s.done)
+		s.db.Close()
+	}
+}
+
+// Set stores value, expiring it according to the policy for its prefix.
+func (s *BadgerStore) Set(key, value []byte) error {
+	p := s.policy(key)
+	if p.MaxSize > 0 {
+		s.usage.touch(key)
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry(key, seal(key, value, time.Now()))
+		if ttl := p.expiry(); ttl > 0 {
+			e = e.WithTTL(ttl)
+		}
+		return txn.SetEntry(e)
+	})
+}
+
+func (s *BadgerStore) Get(key []byte) ([]byte, error) {
+	value, _, err := s.GetFresh(key)
+	return value, err
+}
+
+// GetFresh returns the value for key and whether it is still within its TTL.
+// Stale values are returned until they expire so callers can serve them
+// while refreshing.
+func (s *BadgerStore) GetFresh(key []byte) ([]byte, bool, error) {
+	value, fresh, err := s.getFresh(key)
+	recordLookup(key, fresh, err)
+	return value, fresh, err
+}
+
+func (s *BadgerStore) getFresh(key []byte) ([]byte, bool, error) {
+	var value []byte
+	fresh := true
+	p := s.policy(key)
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if exp := item.ExpiresAt(); exp > 0 {
+			staleAt := time.Unix(int64(exp), 0).Add(-p.Stale)
+			fresh = time.Now().Before(staleAt)
+		}
+		raw, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		value, err = unseal(key, raw)
+		return err
+	})
+	if errors.Is(err, errOutdated) {
+		slog.Info("dropping outdated cache entry", "key", string(key))
+		if err := s.Delete(key); err != nil {
+			return nil, false, err
+		}
+		return nil, false, ErrNotFound
+	}
+	if err == nil && p.MaxSize > 0 {
+		s.usage.touch(key)
+	}
+	return value, fresh, err
+}
+
+func (s *BadgerStore) Delete(key []byte) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+func (s *BadgerStore) GetKeys(prefix []byte) ([][]byte, error) {
+	keys := make([][]byte, 0)
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			k := item.KeyCopy(nil)
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// Scan calls fn for every entry under prefix, with the write time recorded
+// in its envelope.
+func (s *BadgerStore) Scan(prefix []byte, fn func(EntryInfo) error) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = prefix
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			e := EntryInfo{
+				Key:  item.KeyCopy(nil),
+				Size: int64(len(item.Key())) + item.ValueSize(),
+			}
+			err := item.Value(func(v []byte) error {
+				e.Updated, _ = sealedAt(v)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// badgerLogger sends badger's logs to slog. Badger is chatty at info level,
+// so that is logged as debug.
+type badgerLogger struct{}
+
+func (badgerLogger) Errorf(f string, v ...interface{}) {
+	slog.Error(strings.TrimSpace(fmt.Sprintf(f, v...)), "component", "badger")
+}
+
+func (badgerLogger) Warningf(f string, v ...interface{}) {
+	slog.Warn(strings.TrimSpace(fmt.Sprintf(f, v...)), "component", "badger")
+}
+
+func (badgerLogger) Infof(f string, v ...interface{}) {
+	slog.Debug(strings.TrimSpace(fmt.Sprintf(f, v...)), "component", "badger")
+}
+
+func (badgerLogger) Debugf(f string, v ...interface{}) {
+	slog.Debug(strings.TrimSpace(fmt.Sprintf(f, v...)), "component", "badger")
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Values are stored in an envelope recording the schema version of the
+// type they hold and when they were written:
+//
+//	magic (1) | version (2, big endian) | unix seconds (8) | data
+//
+// Bump the version for a prefix whenever the type cached under it changes
+// shape; entries written with another version are dropped on read so they
+// get refetched rather than decoding to partial data.
+var schemaVersions = map[string]uint16{
+	"user:":    1,
+	"channel:": 1,
+	"embed:":   1,
+	"signer:":  1,
+}
+
+const (
+	envelopeMagic  = 0xfe
+	envelopeHeader = 11
+)
+
+var errOutdated = errors.New("outdated cache entry")
+
+func schemaVersion(key []byte) uint16 {
+	for prefix, v := range schemaVersions {
+		if strings.HasPrefix(string(key), prefix) {
+			return v
+		}
+	}
+	return 1
+}
+
+func seal(key, value []byte, ts time.Time) []byte {
+	b := make([]byte, envelopeHeader+len(value))
+	b[0] = envelopeMagic
+	binary.BigEndian.PutUint16(b[1:3], schemaVersion(key))
+	binary.BigEndian.PutUint64(b[3:11], uint64(ts.Unix()))
+	copy(b[envelopeHeader:], value)
+	return b
+}
+
+func sealed(raw []byte) bool {
+	return len(raw) >= envelopeHeader && raw[0] == envelopeMagic
+}
+
+// sealedAt returns when an envelope was written, false for values without
+// one.
+func sealedAt(raw []byte) (time.Time, bool) {
+	if !sealed(raw) {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(raw[3:11])), 0), true
+}
+
+// unseal returns the data in an envelope, or errOutdated if it was written
+// with a different schema version or without an envelope at all.
+func unseal(key, raw []byte) ([]byte, error) {
+	if !sealed(raw) {
+		return nil, errOutdated
+	}
+	if binary.BigEndian.Uint16(raw[1:3]) != schemaVersion(key) {
+		return nil, errOutdated
+	}
+	return raw[envelopeHeader:], nil
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// usage records when keys under a size capped prefix were last read or
+// written, so eviction can drop the least recently used first. It is only
+// kept in memory; entries without a recorded use fall back to their write
+// time.
+type usage struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func newUsage() *usage {
+	return &usage{used: make(map[string]time.Time)}
+}
+
+func (u *usage) touch(key []byte) {
+	u.mu.Lock()
+	u.used[string(key)] = time.Now()
+	u.mu.Unlock()
+}
+
+func (u *usage) forget(key []byte) {
+	u.mu.Lock()
+	delete(u.used, string(key))
+	u.mu.Unlock()
+}
+
+func (u *usage) lastUsed(e EntryInfo) time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if t, ok := u.used[string(e.Key)]; ok {
+		return t
+	}
+	return e.Updated
+}
+
+// evictLRU deletes the least recently used entries under prefix until their
+// total size is at most max. It returns the number of entries deleted.
+func evictLRU(s Store, u *usage, prefix string, max int64) (int, error) {
+	var entries []EntryInfo
+	var total int64
+	err := s.Scan([]byte(prefix), func(e EntryInfo) error {
+		entries = append(entries, e)
+		total += e.Size
+		return nil
+	})
+	if err != nil || total <= max {
+		return 0, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return u.lastUsed(entries[i]).Before(u.lastUsed(entries[j]))
+	})
+	n := 0
+	for _, e := range entries {
+		if total <= max {
+			break
+		}
+		if err := s.Delete(e.Key); err != nil {
+			return n, err
+		}
+		u.forget(e.Key)
+		total -= e.Size
+		n++
+	}
+	slog.Info("evicted cache entries", "prefix", prefix, "count", n)
+	return n, nil
+}
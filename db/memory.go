@@ -0,0 +1,123 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/treethought/tofui/config"
+)
+
+type memEntry struct {
+	value     []byte
+	updated   time.Time
+	expiresAt time.Time
+}
+
+func (e memEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// MemoryStore is a Store that lives only as long as the process, used for
+// tests and when running without a cache.
+type MemoryStore struct {
+	*policySet
+	mu      sync.RWMutex
+	entries map[string]memEntry
+}
+
+func NewMemoryStore(cfg *config.Config) *MemoryStore {
+	return &MemoryStore{
+		policySet: newPolicySet(cfg),
+		entries:   make(map[string]memEntry),
+	}
+}
+
+func (s *MemoryStore) Set(key, value []byte) error {
+	e := memEntry{value: append([]byte(nil), value...), updated: time.Now()}
+	if ttl := s.policy(key).expiry(); ttl > 0 {
+		e.expiresAt = e.updated.Add(ttl)
+	}
+	s.mu.Lock()
+	s.entries[string(key)] = e
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Get(key []byte) ([]byte, error) {
+	value, _, err := s.GetFresh(key)
+	return value, err
+}
+
+func (s *MemoryStore) GetFresh(key []byte) ([]byte, bool, error) {
+	value, fresh, err := s.getFresh(key)
+	recordLookup(key, fresh, err)
+	return value, fresh, err
+}
+
+func (s *MemoryStore) getFresh(key []byte) ([]byte, bool, error) {
+	s.mu.RLock()
+	e, ok := s.entries[string(key)]
+	s.mu.RUnlock()
+	now := time.Now()
+	if !ok || e.expired(now) {
+		return nil, false, ErrNotFound
+	}
+	fresh := true
+	if !e.expiresAt.IsZero() {
+		fresh = now.Before(e.expiresAt.Add(-s.policy(key).Stale))
+	}
+	return append([]byte(nil), e.value...), fresh, nil
+}
+
+func (s *MemoryStore) Delete(key []byte) error {
+	s.mu.Lock()
+	delete(s.entries, string(key))
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) GetKeys(prefix []byte) ([][]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	names := make([]string, 0)
+	for k, e := range s.entries {
+		if strings.HasPrefix(k, string(prefix)) && !e.expired(now) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	keys := make([][]byte, 0, len(names))
+	for _, k := range names {
+		keys = append(keys, []byte(k))
+	}
+	return keys, nil
+}
+
+func (s *MemoryStore) Scan(prefix []byte, fn func(EntryInfo) error) error {
+	keys, err := s.GetKeys(prefix)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		s.mu.RLock()
+		e, ok := s.entries[string(k)]
+		s.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		info := EntryInfo{Key: k, Size: int64(len(k) + len(e.value)), Updated: e.updated}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Ping() error {
+	return nil
+}
+
+func (s *MemoryStore) Close() {}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// schemaKey holds the number of migrations applied to the db. It is stored
+// outside of the envelope.
+const schemaKey = "meta:schema"
+
+type migration struct {
+	name string
+	run  func(*BadgerStore) error
+}
+
+// migrations are applied in order at open time, a migration's version is
+// its index plus one. Only append to this list.
+var migrations = []migration{
+	{"wrap values in versioned envelope", wrapLegacyValues},
+}
+
+func (s *BadgerStore) schema() (int, error) {
+	var v int
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(schemaKey))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			v, err = strconv.Atoi(string(val))
+			return err
+		})
+	})
+	return v, err
+}
+
+func (s *BadgerStore) migrate() error {
+	current, err := s.schema()
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	for i := current; i < len(migrations); i++ {
+		m := migrations[i]
+		slog.Info("migrating db", "version", i+1, "migration", m.name)
+		if err := m.run(s); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", i+1, m.name, err)
+		}
+		err := s.db.Update(func(txn *badger.Txn) error {
+			return txn.Set([]byte(schemaKey), []byte(strconv.Itoa(i+1)))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wrapLegacyValues seals values written before envelopes existed, keeping
+// their expiry. Signers in particular must survive the upgrade. Entries that
+// never expired get the expiry their prefix's policy would give them now,
+// so cached users and channels are refreshed like any other.
+func wrapLegacyValues(s *BadgerStore) error {
+	type legacy struct {
+		key, value []byte
+		expiresAt  uint64
+	}
+	var entries []legacy
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			if string(item.Key()) == schemaKey {
+				continue
+			}
+			v, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if sealed(v) {
+				continue
+			}
+			entries = append(entries, legacy{item.KeyCopy(nil), v, item.ExpiresAt()})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	wb := s.db.NewWriteBatch()
+	defer wb.Cancel()
+	now := time.Now()
+	for _, e := range entries {
+		entry := badger.NewEntry(e.key, seal(e.key, e.value, now))
+		entry.ExpiresAt = e.expiresAt
+		if ttl := s.policy(e.key).expiry(); entry.ExpiresAt == 0 && ttl > 0 {
+			entry.ExpiresAt = uint64(now.Add(ttl).Unix())
+		}
+		if err := wb.SetEntry(entry); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/treethought/tofui/config"
+)
+
+// Policy is the freshness policy for keys under a prefix. Entries are fresh
+// for TTL, then stale for Stale, after which they expire. A zero policy keeps
+// entries forever. When MaxSize is set, the least recently used entries are
+// evicted once the prefix grows past it.
+type Policy struct {
+	TTL     time.Duration
+	Stale   time.Duration
+	MaxSize int64
+}
+
+func (p Policy) expiry() time.Duration {
+	if p.TTL == 0 {
+		return 0
+	}
+	return p.TTL + p.Stale
+}
+
+var defaultPolicies = map[string]Policy{
+	"user:":          {TTL: time.Hour, Stale: 24 * time.Hour},
+	"channel:":       {TTL: 24 * time.Hour, Stale: 7 * 24 * time.Hour},
+	"channelurl:":    {TTL: 24 * time.Hour, Stale: 7 * 24 * time.Hour},
+	"channelsloaded": {TTL: 24 * time.Hour},
+	"embed:":         {TTL: 7 * 24 * time.Hour},
+	"img:":           {TTL: 30 * 24 * time.Hour, MaxSize: 512 << 20},
+}
+
+func policiesFromConfig(cfg *config.Config) map[string]Policy {
+	policies := make(map[string]Policy, len(defaultPolicies))
+	for prefix, p := range defaultPolicies {
+		policies[prefix] = p
+	}
+	override := func(prefix string, cp config.CachePolicy) {
+		p := policies[prefix]
+		if cp.TTL != nil {
+			p.TTL = *cp.TTL
+		}
+		if cp.Stale != 0 {
+			p.Stale = cp.Stale
+		}
+		if cp.MaxSizeMB != 0 {
+			p.MaxSize = cp.MaxSizeMB << 20
+		}
+		policies[prefix] = p
+	}
+	override("user:", cfg.Cache.User)
+	override("channel:", cfg.Cache.Channel)
+	override("channelurl:", cfg.Cache.Channel)
+	override("channelsloaded", cfg.Cache.Channel)
+	override("embed:", cfg.Cache.Embed)
+	override("img:", cfg.Cache.Image)
+	return policies
+}
+
+type policySet struct {
+	mu       sync.RWMutex
+	policies map[string]Policy
+}
+
+func newPolicySet(cfg *config.Config) *policySet {
+	return &policySet{policies: policiesFromConfig(cfg)}
+}
+
+// SetPolicy sets the freshness policy for keys written under prefix.
+func (ps *policySet) SetPolicy(prefix string, p Policy) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.policies[prefix] = p
+}
+
+// limits returns the prefixes that have a size cap.
+func (ps *policySet) limits() map[string]int64 {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	limits := make(map[string]int64)
+	for prefix, p := range ps.policies {
+		if p.MaxSize > 0 {
+			limits[prefix] = p.MaxSize
+		}
+	}
+	return limits
+}
+
+func (ps *policySet) policy(key []byte) Policy {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	var match string
+	var p Policy
+	for prefix, pol := range ps.policies {
+		if strings.HasPrefix(string(key), prefix) && len(prefix) > len(match) {
+			match, p = prefix, pol
+		}
+	}
+	return p
+}
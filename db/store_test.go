@@ -0,0 +1,242 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+
+	"github.com/treethought/tofui/config"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	cfg := &config.Config{}
+	cfg.DB.Dir = t.TempDir()
+	b, err := OpenBadger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(b.Close)
+	return map[string]Store{
+		"badger": b,
+		"memory": NewMemoryStore(cfg),
+	}
+}
+
+func TestStore(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.Get([]byte("user:1")); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			for _, k := range []string{"user:1", "user:2", "channel:a"} {
+				if err := s.Set([]byte(k), []byte(k)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			v, fresh, err := s.GetFresh([]byte("user:1"))
+			if err != nil || string(v) != "user:1" || !fresh {
+				t.Fatalf("unexpected get: %q %v %v", v, fresh, err)
+			}
+
+			keys, err := s.GetKeys([]byte("user:"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 2 {
+				t.Fatalf("expected 2 user keys, got %d", len(keys))
+			}
+			err = s.Scan([]byte("user:"), func(e EntryInfo) error {
+				if time.Since(e.Updated) > time.Minute {
+					t.Errorf("expected %s to be written just now, got %v", e.Key, e.Updated)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.Delete([]byte("user:1")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Get([]byte("user:1")); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected deleted key to be gone, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStorePolicy(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			s.SetPolicy("user:", Policy{TTL: time.Nanosecond, Stale: time.Hour})
+			s.SetPolicy("img:", Policy{TTL: time.Nanosecond})
+
+			if err := s.Set([]byte("user:1"), []byte("alice")); err != nil {
+				t.Fatal(err)
+			}
+			v, fresh, err := s.GetFresh([]byte("user:1"))
+			if err != nil || string(v) != "alice" {
+				t.Fatalf("expected stale value to be served: %q %v", v, err)
+			}
+			if fresh {
+				t.Error("expected value past its ttl to be stale")
+			}
+
+			if err := s.Set([]byte("img:a"), []byte("png")); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Millisecond)
+			if name == "badger" {
+				// badger expiry has second granularity
+				time.Sleep(time.Second)
+			}
+			if _, err := s.Get([]byte("img:a")); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected expired value to be gone, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPoliciesFromConfig(t *testing.T) {
+	cfg := &config.Config{}
+	forever := time.Duration(0)
+	cfg.Cache.User.TTL = &forever
+	p := policiesFromConfig(cfg)
+	if p["user:"].TTL != 0 || p["user:"].expiry() != 0 {
+		t.Errorf("expected a ttl of 0 to keep users forever, got %+v", p["user:"])
+	}
+	if p["img:"] != defaultPolicies["img:"] {
+		t.Errorf("expected unset policies to keep the default, got %+v", p["img:"])
+	}
+}
+
+func TestEvictLRU(t *testing.T) {
+	s := NewMemoryStore(&config.Config{})
+	u := newUsage()
+	for _, k := range []string{"img:a", "img:b", "img:c"} {
+		if err := s.Set([]byte(k), make([]byte, 100)); err != nil {
+			t.Fatal(err)
+		}
+		u.touch([]byte(k))
+		time.Sleep(time.Millisecond)
+	}
+	// reading a makes b the least recently used
+	u.touch([]byte("img:a"))
+
+	n, err := evictLRU(s, u, "img:", 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 eviction, got %d", n)
+	}
+	if _, err := s.Get([]byte("img:b")); !errors.Is(err, ErrNotFound) {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	for _, k := range []string{"img:a", "img:c"} {
+		if _, err := s.Get([]byte(k)); err != nil {
+			t.Errorf("expected %s to be kept: %v", k, err)
+		}
+	}
+}
+
+func TestMigrateLegacyValues(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DB.Dir = t.TempDir()
+	ttl := time.Nanosecond
+	cfg.Cache.User.TTL = &ttl
+	s, err := OpenBadger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// simulate a db written before envelopes existed
+	err = s.db.Update(func(txn *badger.Txn) error {
+		if err := txn.Delete([]byte(schemaKey)); err != nil {
+			return err
+		}
+		if err := txn.Set([]byte("user:1"), []byte(`{"fid":1}`)); err != nil {
+			return err
+		}
+		return txn.Set([]byte("signer:local"), []byte(`{"FID":1}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = OpenBadger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	v, err := s.Get([]byte("signer:local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != `{"FID":1}` {
+		t.Errorf("expected legacy signer to survive migration, got %q", v)
+	}
+	// legacy cache entries never expired, they now follow the user policy
+	v, fresh, err := s.GetFresh([]byte("user:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != `{"fid":1}` || fresh {
+		t.Errorf("expected migrated user to be served stale once its ttl passed, got %q fresh=%v", v, fresh)
+	}
+	// entries that never expire still know when they were written, so they
+	// can be pruned
+	_ = s.Scan([]byte("signer:"), func(e EntryInfo) error {
+		if e.Updated.IsZero() {
+			t.Errorf("expected migrated %s to have a write time", e.Key)
+		}
+		return nil
+	})
+	if version, _ := s.schema(); version != len(migrations) {
+		t.Errorf("expected schema version %d, got %d", len(migrations), version)
+	}
+}
+
+func TestPingLeavesSchema(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DB.Dir = t.TempDir()
+	s, err := OpenBadger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for i := 0; i < 2; i++ {
+		if err := s.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if version, _ := s.schema(); version != len(migrations) {
+		t.Errorf("expected ping to leave schema version %d, got %d", len(migrations), version)
+	}
+}
+
+func TestOutdatedEntryDropped(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.DB.Dir = t.TempDir()
+	s, err := OpenBadger(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Set([]byte("user:1"), []byte(`{"fid":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	schemaVersions["user:"]++
+	defer func() { schemaVersions["user:"]-- }()
+
+	if _, err := s.Get([]byte("user:1")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected outdated entry to read as missing, got %v", err)
+	}
+	keys, _ := s.GetKeys([]byte("user:"))
+	if len(keys) != 0 {
+		t.Error("expected outdated entry to be deleted")
+	}
+}
@@ -0,0 +1,97 @@
+// Package logging sets up the structured logger shared by tofui's packages.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/treethought/tofui/config"
+)
+
+const defaultPath = "tofui.log"
+
+// Setup makes a slog logger writing to the configured log file the default,
+// for slog and the standard log package alike. A path of "-" logs to stderr,
+// which suits the SSH server but not the local app. The returned closer
+// flushes the log file.
+func Setup(cfg *config.Config) (io.Closer, error) {
+	lc := cfg.Log
+	level, err := ParseLevel(lc.Level)
+	if err != nil {
+		return nil, err
+	}
+	w, err := logWriter(lc.Path, lc.MaxSizeMB, lc.MaxBackups, lc.MaxAgeDays)
+	if err != nil {
+		return nil, err
+	}
+
+	// API keys never appear in logs, wherever they end up
+	Redact(cfg.Neynar.APIKey)
+	slog.SetDefault(slog.New(NewRedactHandler(newHandler(w, lc.Format, level))))
+	// the standard logger is routed through slog at info level
+	log.SetFlags(0)
+	return w, nil
+}
+
+func logWriter(path string, maxSizeMB, maxBackups, maxAgeDays int) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stderr}, nil
+	}
+	if path == "" {
+		path = defaultPath
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if maxSizeMB == 0 {
+		maxSizeMB = 50
+	}
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAgeDays,
+	}, nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func newHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+	if format == "json" {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// ParseLevel parses debug, info, warn or error. An empty level is info.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := l.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return l, fmt.Errorf("invalid log level %q", s)
+	}
+	return l, nil
+}
+
+// PrintfLogger adapts slog to libraries that log with Printf.
+type PrintfLogger struct {
+	Component string
+}
+
+func (l PrintfLogger) Printf(format string, v ...interface{}) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)), "component", l.Component)
+}
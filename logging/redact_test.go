@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactHandler(t *testing.T) {
+	Redact("sekrit-api-key")
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil)))
+
+	logger.With("signer_uuid", "abc-123").Info(
+		`sending payload: {"signer_uuid":"abc-123","text":"gm"}`,
+		"url", "http://localhost:4200/signin?nonce=deadbeef",
+		"error", errors.New("bad key sekrit-api-key"),
+		"fid", 42,
+	)
+	out := buf.String()
+	for _, secret := range []string{"abc-123", "deadbeef", "sekrit-api-key"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %q to be redacted: %s", secret, out)
+		}
+	}
+	for _, kept := range []string{"gm", "fid=42", "/signin?nonce="} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("expected %q to be kept: %s", kept, out)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"": slog.LevelInfo, "debug": slog.LevelDebug, "WARN": slog.LevelWarn} {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v", in, got, err)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected invalid level to fail")
+	}
+}
@@ -0,0 +1,44 @@
+// Package metrics defines the Prometheus metrics exported by tofui when it
+// is served over SSH.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	SSHSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "tofui_ssh_sessions",
+		Help: "Active SSH sessions.",
+	})
+	SSHSessionsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tofui_ssh_sessions_rejected_total",
+		Help: "SSH sessions turned away, by reason.",
+	}, []string{"reason"})
+
+	Signins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tofui_signins_total",
+		Help: "Sign in callbacks, by result.",
+	}, []string{"result"})
+
+	NeynarRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tofui_neynar_requests_total",
+		Help: "Requests to the Neynar API, by path and status code.",
+	}, []string{"method", "path", "status"})
+	NeynarLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tofui_neynar_request_duration_seconds",
+		Help:    "Latency of requests to the Neynar API.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tofui_cache_lookups_total",
+		Help: "Cache lookups by key prefix and result: hit, stale or miss.",
+	}, []string{"prefix", "result"})
+
+	ImageFetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tofui_image_fetch_failures_total",
+		Help: "Images that could not be shown, by stage: download or decode.",
+	}, []string{"stage"})
+)
@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// animInterval is how often animations on screen are advanced
+	animInterval = 50 * time.Millisecond
+	// animIdle is how often the ticker checks back while every animation
+	// is off screen
+	animIdle = time.Second
+	// animForget drops animations that haven't been drawn for this long
+	animForget = time.Minute
+)
+
+type animTickMsg time.Time
+
+// animation is an image with more than one frame, already drawn.
+type animation struct {
+	frames []string
+	delays []time.Duration
+	frame  int
+	next   time.Time
+}
+
+func newAnimation(frames []string, delays []time.Duration) *animation {
+	return &animation{frames: frames, delays: delays}
+}
+
+func (an *animation) current() string {
+	return an.frames[an.frame]
+}
+
+// advance moves to the next frame once the current one has been shown for
+// its delay.
+func (an *animation) advance(now time.Time) {
+	if an.next.IsZero() {
+		an.next = now.Add(an.delays[an.frame])
+		return
+	}
+	if now.Before(an.next) {
+		return
+	}
+	an.frame = (an.frame + 1) % len(an.frames)
+	an.next = now.Add(an.delays[an.frame])
+}
+
+// showAnimation records that an was drawn, which keeps it playing.
+func (a *App) showAnimation(an *animation) {
+	a.animations[an] = time.Now()
+}
+
+// animate starts the ticker when there are animations and it isn't running.
+func (a *App) animate() tea.Cmd {
+	if a.animTicking || len(a.animations) == 0 {
+		return nil
+	}
+	a.animTicking = true
+	d := animIdle
+	if a.animVisible {
+		d = animInterval
+	}
+	return tea.Tick(d, func(t time.Time) tea.Msg {
+		return animTickMsg(t)
+	})
+}
+
+// tickAnimations advances the animations drawn since the last tick. The
+// others are paused until they're drawn again, and forgotten after a while.
+func (a *App) tickAnimations(now time.Time) tea.Cmd {
+	a.animTicking = false
+	a.animVisible = false
+	for an, seen := range a.animations {
+		switch {
+		case now.Sub(seen) > animForget:
+			delete(a.animations, an)
+		case !seen.Before(a.lastAnimTick):
+			a.animVisible = true
+			an.advance(now)
+		}
+	}
+	a.lastAnimTick = now
+	return a.animate()
+}
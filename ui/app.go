@@ -1,35 +1,32 @@
 package ui
 
 import (
-	"crypto/sha256"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/ssh"
 
 	"github.com/treethought/tofui/api"
+	"github.com/treethought/tofui/auth"
 	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
 )
 
-// TODO provide to models
-var renderer *lipgloss.Renderer = lipgloss.DefaultRenderer()
-
-var activeColor = lipgloss.AdaptiveColor{Dark: "#874BFD", Light: "#874BFD"}
-
-var (
-	mainStyle = lipgloss.NewStyle().Margin(0).Padding(0).Border(lipgloss.RoundedBorder())
-)
-
-func NewStyle() lipgloss.Style {
-	return renderer.NewStyle()
-}
-
 type UpdateSignerMsg struct {
 	Signer *api.Signer
 }
 
+// signerRevokedMsg is sent when Neynar rejects the signer of the active
+// account.
+type signerRevokedMsg struct {
+	signer *api.Signer
+}
+
 type navNameMsg struct {
 	name string
 }
@@ -40,6 +37,19 @@ func navNameCmd(name string) tea.Cmd {
 	}
 }
 
+// BroadcastMsg shows a notice from the server operator in the status line.
+// An empty Text clears it.
+type BroadcastMsg struct {
+	Text string
+}
+
+// SessionInfo describes what a session is doing, for the server's admin
+// commands.
+type SessionInfo struct {
+	Username string
+	View     string
+}
+
 type SelectCastMsg struct {
 	cast *api.Cast
 }
@@ -48,11 +58,18 @@ type AppContext struct {
 	s      ssh.Session
 	signer *api.Signer
 	pk     string
+	// r draws for the session's terminal, lipgloss's default when local
+	r *lipgloss.Renderer
+	// env looks up the terminal's environment, used to pick how images
+	// are drawn
+	env func(string) string
 }
 
 type App struct {
 	ctx           *AppContext
 	client        *api.Client
+	store         db.Store
+	nonces        *auth.Nonces
 	cfg           *config.Config
 	pubonly       bool
 	focusedModel  tea.Model
@@ -60,15 +77,41 @@ type App struct {
 	navname       string
 	sidebar       *Sidebar
 	showSidebar   bool
-	prev          string
+	history       []historyEntry
 	prevName      string
 	quickSelect   *QuickSelect
 	publish       *PublishInput
 	statusLine    *StatusLine
 	notifications *NotificationsView
 
-	splash *SplashView
-	help   *HelpView
+	splash   *SplashView
+	help     *HelpView
+	keys     *Keys
+	theme    *Theme
+	styles   *styles
+	graphics graphics
+	themes   []*Theme
+	themeIdx int
+
+	imageFetches chan struct{}
+	idleTimeout  time.Duration
+	watchingIdle bool
+	lastInput    time.Time
+	timedOut     bool
+
+	// animations holds when each animated image was last drawn
+	animations   map[*animation]time.Time
+	animTicking  bool
+	animVisible  bool
+	lastAnimTick time.Time
+
+	// width and height are the session's terminal size
+	width  int
+	height int
+
+	notice string
+	// info is read by the server from other goroutines
+	info atomic.Value
 
 	feed    *FeedView
 	channel *FeedView
@@ -80,52 +123,71 @@ func (a *App) PublicKey() string {
 	return a.ctx.pk
 }
 
-func NewSSHApp(cfg *config.Config, s ssh.Session, r *lipgloss.Renderer) (*App, error) {
-	if r != nil {
-		renderer = r
-	}
+func NewSSHApp(cfg *config.Config, client *api.Client, nonces *auth.Nonces, s ssh.Session, r *lipgloss.Renderer) (*App, error) {
 	if s.PublicKey() == nil {
 		return nil, fmt.Errorf("public key is nil")
 	}
 	// hash the pk so we can use it in auth flow
-	h := sha256.New()
-	pkBytes := s.PublicKey().Marshal()
-	h.Write(pkBytes)
-	pk := fmt.Sprintf("%x", h.Sum(nil))
+	pk := auth.KeyHash(s.PublicKey())
 
-	signer := api.GetSigner(pk)
+	signer := client.GetSigner(pk)
 	if signer != nil {
-		log.Println("logged in as: ", signer.Username)
+		slog.Info("logged in", "fid", signer.FID, "pk", pk)
 	}
 
-	ctx := &AppContext{s: s, pk: pk, signer: signer}
-	app := NewApp(cfg, ctx, false)
+	ctx := &AppContext{s: s, pk: pk, signer: signer, r: r, env: sessionEnv(s)}
+	app := NewApp(cfg, client, ctx, false)
+	app.nonces = nonces
+	app.idleTimeout = cfg.Server.Limits.IdleTimeout
 	return app, nil
 }
 
-func NewLocalApp(cfg *config.Config, pubInit bool) *App {
-	signer := api.GetSigner("local")
+func NewLocalApp(cfg *config.Config, client *api.Client, nonces *auth.Nonces, pubInit bool) *App {
+	signer := client.GetSigner("local")
 	if signer != nil {
-		log.Println("logged in locally as: ", signer.Username)
+		slog.Info("logged in locally", "fid", signer.FID)
 	}
-	ctx := &AppContext{signer: signer, pk: "local"}
-	app := NewApp(cfg, ctx, pubInit)
+	ctx := &AppContext{signer: signer, pk: "local", env: os.Getenv}
+	app := NewApp(cfg, client, ctx, pubInit)
+	app.nonces = nonces
 	return app
 }
 
-func NewApp(cfg *config.Config, ctx *AppContext, pubonly bool) *App {
+func NewApp(cfg *config.Config, client *api.Client, ctx *AppContext, pubonly bool) *App {
 	if ctx == nil {
 		ctx = &AppContext{}
 	}
 	a := &App{
 		showSidebar: true,
 		ctx:         ctx,
-		client:      api.NewClient(cfg),
+		client:      client,
+		store:       client.Store(),
 		cfg:         cfg,
 		pubonly:     pubonly,
+		animations:  map[*animation]time.Time{},
+	}
+	keys, err := NewKeys(cfg.Keys)
+	if err != nil {
+		slog.Warn("invalid keys config, using default keys", "error", err)
+		keys = DefaultKeys()
+	}
+	a.keys = keys
+	a.themes = themes(cfg.Theme)
+	a.theme = a.themes[0]
+	if ctx.r == nil {
+		ctx.r = lipgloss.DefaultRenderer()
 	}
+	a.styles = newStyles(ctx.r, a.theme)
+	a.graphics = newGraphics(cfg.Images.Protocol, ctx.env)
+	slog.Debug("drawing images", "protocol", a.graphics)
+	fetches := cfg.Server.Limits.ImageFetches
+	if fetches <= 0 {
+		fetches = defaultImageFetches
+	}
+	a.imageFetches = make(chan struct{}, fetches)
 	a.feed = NewFeedView(a, feedTypeFollowing)
 	a.focusedModel = a.feed
+	a.focused = "feed"
 
 	a.profile = NewProfile(a)
 
@@ -137,7 +199,7 @@ func NewApp(cfg *config.Config, ctx *AppContext, pubonly bool) *App {
 	a.quickSelect = NewQuickSelect(a)
 	a.publish = NewPublishInput(a)
 	a.statusLine = NewStatusLine(a)
-	a.help = NewHelpView(a, GlobalKeyMap)
+	a.help = NewHelpView(a, a.keys)
 	a.notifications = NewNotificationsView(a)
 	a.splash = NewSplashView(a)
 	a.splash.SetActive(true)
@@ -151,10 +213,38 @@ func NewApp(cfg *config.Config, ctx *AppContext, pubonly bool) *App {
 		return a
 	}
 	a.SetNavName("feed")
+	a.publishInfo()
 
 	return a
 }
 
+// logger returns the default logger with the session's public key hash and
+// signed in fid, so log lines can be traced to an SSH user.
+func (a *App) logger() *slog.Logger {
+	l := slog.Default()
+	if a.ctx.s != nil {
+		l = l.With("pk", a.ctx.pk)
+	}
+	if a.ctx.signer != nil {
+		l = l.With("fid", a.ctx.signer.FID)
+	}
+	return l
+}
+
+func (a *App) publishInfo() {
+	info := SessionInfo{View: a.navname}
+	if a.ctx.signer != nil {
+		info.Username = a.ctx.signer.Username
+	}
+	a.info.Store(info)
+}
+
+// Info returns a snapshot of the session, safe to call while the app runs.
+func (a *App) Info() SessionInfo {
+	info, _ := a.info.Load().(SessionInfo)
+	return info
+}
+
 func (a *App) SetNavName(name string) {
 	a.prevName = a.navname
 	a.navname = name
@@ -195,27 +285,51 @@ func (a *App) ToggleHelp() {
 	a.help.SetFull(!a.help.IsFull())
 }
 
-func (a *App) FocusFeed() tea.Cmd {
+// maxHistory bounds the number of views remembered for FocusPrev.
+const maxHistory = 50
+
+type historyEntry struct {
+	focused string
+	navname string
+	// cast is the cast that was shown, when focused is "cast"
+	cast *api.Cast
+}
+
+// pushHistory remembers the focused view for FocusPrev.
+func (a *App) pushHistory() {
+	e := historyEntry{focused: a.focused, navname: a.navname}
+	if a.focused == "cast" {
+		e.cast = a.cast.cast
+	}
+	a.history = append(a.history, e)
+	if len(a.history) > maxHistory {
+		a.history = a.history[1:]
+	}
+}
+
+// setFocus focuses the main view m, remembering the previously focused view.
+func (a *App) setFocus(name string, m tea.Model) {
 	a.focusMain()
-	a.SetNavName("feed")
-	a.focusedModel = a.feed
-	a.focused = "feed"
+	if a.focused != "" && a.focused != name {
+		a.pushHistory()
+	}
+	a.SetNavName(name)
+	a.focusedModel = m
+	a.focused = name
+}
+
+func (a *App) FocusFeed() tea.Cmd {
+	a.setFocus("feed", a.feed)
 	return nil
 }
 
 func (a *App) FocusProfile() tea.Cmd {
-	a.focusMain()
-	a.SetNavName("profile")
-	a.focusedModel = a.profile
-	a.focused = "profile"
+	a.setFocus("profile", a.profile)
 	return a.profile.Init()
 }
 
 func (a *App) FocusChannel() tea.Cmd {
-	a.focusMain()
-	a.SetNavName("channel")
-	a.focusedModel = a.channel
-	a.focused = "channel"
+	a.setFocus("channel", a.channel)
 	return a.channel.Init()
 }
 
@@ -223,37 +337,140 @@ func (a *App) GoToCast(hash string) tea.Cmd {
 	return func() tea.Msg {
 		cast, err := a.client.GetCastWithReplies(a.ctx.signer, hash)
 		if err != nil {
-			log.Println("error getting cast: ", err)
+			slog.Error("error getting cast", "error", err)
 			return nil
 		}
-		return tea.Sequence(a.FocusCast(), a.cast.SetCast(cast))
+		return SelectCastMsg{cast: cast}
 	}
 }
 
 func (a *App) FocusCast() tea.Cmd {
-	a.focusMain()
-	a.SetNavName("cast")
-	a.focusedModel = a.cast
-	a.focused = "cast"
+	a.setFocus("cast", a.cast)
 	return a.cast.Init()
 }
 
+// NextTheme switches to the next theme for this session.
+func (a *App) NextTheme() {
+	a.themeIdx = (a.themeIdx + 1) % len(a.themes)
+	a.theme = a.themes[a.themeIdx]
+	a.styles = newStyles(a.ctx.r, a.theme)
+	a.notice = fmt.Sprintf("theme: %s", a.theme.Name)
+}
+
+// SwitchAccount makes the next account signed in with this public key the
+// active one.
+func (a *App) SwitchAccount() tea.Cmd {
+	accounts := a.client.Signers(a.ctx.pk)
+	if len(accounts) < 2 {
+		return nil
+	}
+	next := accounts[0]
+	for i, s := range accounts {
+		if a.ctx.signer != nil && s.FID == a.ctx.signer.FID {
+			next = accounts[(i+1)%len(accounts)]
+		}
+	}
+	return a.UseAccount(next.FID)
+}
+
+func (a *App) UseAccount(fid uint64) tea.Cmd {
+	return func() tea.Msg {
+		signer, err := a.client.UseSigner(a.ctx.pk, fid)
+		if err != nil {
+			slog.Error("failed to switch account", "error", err)
+			return nil
+		}
+		return &UpdateSignerMsg{Signer: signer}
+	}
+}
+
+// SignOut removes the active account. In SSH mode this unlinks the public
+// key from the account. If another account is signed in it becomes active,
+// otherwise the sign in screen is shown.
+func (a *App) SignOut() tea.Cmd {
+	if a.ctx.signer == nil {
+		return nil
+	}
+	if err := a.client.RemoveSigner(a.ctx.pk, a.ctx.signer.FID); err != nil {
+		a.logger().Error("failed to sign out", "error", err)
+		return nil
+	}
+	a.logger().Info("signed out")
+	if next := a.client.GetSigner(a.ctx.pk); next != nil {
+		return a.UseAccount(next.FID)
+	}
+	return a.signedOut("Signed out. Press Enter to sign in")
+}
+
+// signerRevoked removes an account whose signer is no longer valid and asks
+// the user to sign in again.
+func (a *App) signerRevoked(s *api.Signer) tea.Cmd {
+	if s == nil {
+		return nil
+	}
+	a.logger().Warn("signer revoked", "revoked_fid", s.FID)
+	if err := a.client.RemoveSigner(a.ctx.pk, s.FID); err != nil {
+		a.logger().Error("failed to remove revoked signer", "error", err)
+	}
+	reason := fmt.Sprintf("Your sign in for @%s is no longer valid. Press Enter to sign in again", s.Username)
+	if next := a.client.GetSigner(a.ctx.pk); next != nil {
+		reason += fmt.Sprintf(", or Esc to continue as @%s", next.Username)
+	}
+	return a.signedOut(reason)
+}
+
+func (a *App) signedOut(reason string) tea.Cmd {
+	a.ctx.signer = nil
+	a.focusMain()
+	a.feed.Clear()
+	a.FocusFeed()
+	a.history = nil
+	a.sidebar.account = nil
+	a.splash.SetActive(true)
+	a.splash.ShowSignin(true)
+	a.splash.SetSigninInfo(reason)
+	return a.sidebar.Init()
+}
+
+// AddAccount shows the sign in screen so another account can be added.
+func (a *App) AddAccount() {
+	a.sidebar.SetActive(false)
+	a.splash.SetActive(true)
+	a.splash.ShowSignin(true)
+}
+
 func (a *App) GetFocused() tea.Model {
 	return a.focusedModel
 }
 
+// FocusPrev returns to the previously focused view, falling back to the feed
+// once the history is exhausted.
 func (a *App) FocusPrev() tea.Cmd {
-	switch a.prev {
-	case "feed":
+	if len(a.history) == 0 {
 		return a.FocusFeed()
+	}
+	n := len(a.history) - 1
+	prev := a.history[n]
+	a.history = a.history[:n]
+
+	var cmd tea.Cmd
+	switch prev.focused {
 	case "profile":
-		return a.FocusProfile()
+		cmd = a.FocusProfile()
 	case "channel":
-		return a.FocusChannel()
+		cmd = a.FocusChannel()
 	case "cast":
-		return a.FocusCast()
+		cmd = a.FocusCast()
+		if prev.cast != nil && prev.cast != a.cast.cast {
+			cmd = tea.Sequence(a.cast.SetCast(prev.cast), cmd)
+		}
+	default:
+		cmd = a.FocusFeed()
 	}
-	return a.FocusFeed()
+	// going back should not record the view we are leaving
+	a.history = a.history[:n]
+	a.SetNavName(prev.navname)
+	return cmd
 }
 
 func (a *App) Init() tea.Cmd {
@@ -262,6 +479,7 @@ func (a *App) Init() tea.Cmd {
 		a.splash.Init(), a.sidebar.Init(),
 		a.quickSelect.Init(), a.publish.Init(),
 		a.notifications.Init(),
+		a.watchIdle(),
 	)
 	focus := a.GetFocused()
 	if focus != nil {
@@ -270,25 +488,52 @@ func (a *App) Init() tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
-func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (a *App) Update(msg tea.Msg) (_ tea.Model, cmd tea.Cmd) {
+	defer a.publishInfo()
+	// images that started animating start the ticker if it's stopped
+	defer func() { cmd = tea.Batch(cmd, a.animate()) }()
 
-	// log.Println("received msg type: ", reflect.TypeOf(msg))
 	var cmds []tea.Cmd
 	_, sbcmd := a.statusLine.Update(msg)
 	cmds = append(cmds, sbcmd)
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		a.lastInput = time.Now()
+	}
 	switch msg := msg.(type) {
+	case idleCheckMsg:
+		return a, a.checkIdle()
+	case animTickMsg:
+		return a, a.tickAnimations(time.Time(msg))
+	case signinPollMsg:
+		_, cmd := a.splash.Update(msg)
+		return a, cmd
+	case BroadcastMsg:
+		a.notice = msg.Text
+		_, cmd := a.statusLine.Update(msg)
+		return a, cmd
 	case *notificationsMsg:
 		_, cmd := a.notifications.Update(msg)
 		return a, cmd
 	case *UpdateSignerMsg:
 		a.ctx.signer = msg.Signer
 		a.splash.ShowSignin(false)
-		log.Println("updated signer for: ", msg.Signer.Username)
+		a.logger().Info("updated signer")
+		// everything loaded so far belongs to the previous account
+		a.feed.Clear()
+		a.FocusFeed()
+		a.history = nil
 		return a, a.Init()
+	case *signerRevokedMsg:
+		return a, a.signerRevoked(msg.signer)
 	case navNameMsg:
 		a.SetNavName(msg.name)
 		return a, nil
 	case *postResponseMsg:
+		if a.focused == "cast" && a.cast.pubReply.Active() {
+			_, cmd := a.cast.pubReply.Update(msg)
+			return a, cmd
+		}
 		_, cmd := a.publish.Update(msg)
 		return a, tea.Sequence(cmd, a.FocusPrev())
 	case *channelListMsg:
@@ -313,21 +558,25 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.cast.ParentHash != "" {
 			nav = fmt.Sprintf("reply by @%s", msg.cast.Author.Username)
 		}
+		// moving between casts stays in the cast view, remember the last one
+		if a.focused == "cast" && a.cast.cast != nil && a.cast.cast.Hash != msg.cast.Hash {
+			a.pushHistory()
+		}
+		fcmd := a.FocusCast()
 		a.SetNavName(nav)
 		return a, tea.Sequence(
 			a.cast.SetCast(msg.cast),
-			a.FocusCast(),
+			fcmd,
 		)
 
 	case tea.WindowSizeMsg:
-		SetHeight(msg.Height)
-		SetWidth(msg.Width)
+		a.width, a.height = msg.Width, msg.Height
 
 		a.statusLine.SetSize(msg.Width, 1)
 		_, statusHeight := lipgloss.Size(a.statusLine.View())
 
 		wx, wy := msg.Width, msg.Height-statusHeight
-		fx, fy := mainStyle.GetFrameSize()
+		fx, fy := a.styles.main.GetFrameSize()
 		wx = wx - fx
 		wy = wy - fy
 
@@ -364,22 +613,30 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, fcmd, pcmd, ccmd, cscmd)
 
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "q":
+		if msg.String() == "ctrl+c" {
 			return a, tea.Quit
 		}
-		cmd := NavKeyMap.HandleMsg(a, msg)
-		if cmd != nil {
+		// text inputs take keys before global bindings so typing isn't hijacked
+		if a.publish.Active() {
+			_, cmd := a.publish.Update(msg)
 			return a, cmd
 		}
-		if a.sidebar.Active() {
-			_, cmd := a.sidebar.Update(msg)
+		if a.focused == "cast" && a.cast.pubReply.Active() {
+			_, cmd := a.cast.Update(msg)
 			return a, cmd
 		}
 		if a.splash.Active() {
 			_, cmd := a.splash.Update(msg)
 			return a, cmd
 		}
+		cmd := a.keys.Nav.HandleMsg(a, msg)
+		if cmd != nil {
+			return a, cmd
+		}
+		if a.sidebar.Active() {
+			_, cmd := a.sidebar.Update(msg)
+			return a, cmd
+		}
 		if a.publish.Active() {
 			_, cmd := a.publish.Update(msg)
 			return a, cmd
@@ -421,12 +678,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	current := a.GetFocused()
 	if current == nil {
-		log.Println("no focused model")
+		slog.Debug("no focused model")
 		return Fallback, nil
 	}
 
-	_, cmd := current.Update(msg)
-	cmds = append(cmds, cmd)
+	_, ccmd := current.Update(msg)
+	cmds = append(cmds, ccmd)
 	return a, tea.Batch(cmds...)
 
 }
@@ -439,7 +696,7 @@ func (a *App) View() string {
 	main := focus.View()
 	side := a.sidebar.View()
 	if a.splash.Active() {
-		main = lipgloss.Place(GetWidth(), GetHeight(), lipgloss.Center, lipgloss.Center, a.splash.View())
+		main = lipgloss.Place(a.width, a.height, lipgloss.Center, lipgloss.Center, a.splash.View())
 		return main
 	}
 	if a.notifications.Active() {
@@ -453,16 +710,16 @@ func (a *App) View() string {
 		main = a.quickSelect.View()
 	}
 	if !a.showSidebar {
-		return NewStyle().Align(lipgloss.Center).Render(main)
+		return a.styles.New().Align(lipgloss.Center).Render(main)
 	}
 
 	if a.help.IsFull() {
 		main = a.help.View()
 	}
 
-	ss := mainStyle
+	ss := a.styles.main
 	if !a.sidebar.Active() {
-		ss = ss.BorderForeground(activeColor)
+		ss = ss.BorderForeground(a.theme.Accent)
 	}
 	main = ss.Render(main)
 
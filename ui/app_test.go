@@ -0,0 +1,444 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/muesli/termenv"
+
+	"github.com/treethought/tofui/api"
+	"github.com/treethought/tofui/api/neynartest"
+	"github.com/treethought/tofui/auth"
+	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
+)
+
+var (
+	testServer *neynartest.Server
+	testCfg    *config.Config
+	testClient *api.Client
+	testSigner = &api.Signer{
+		FID:         1,
+		UUID:        neynartest.SignerUUID,
+		Username:    "tofui",
+		DisplayName: "tofui",
+		PublicKey:   "local",
+	}
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	testServer = neynartest.NewServer()
+
+	testCfg = &config.Config{}
+	testCfg.Neynar.APIKey = neynartest.APIKey
+	testCfg.Neynar.BaseUrl = testServer.URL
+	testCfg.Server.Host = "localhost"
+	testCfg.Server.HTTPPort = 4200
+	testClient = api.NewClient(testCfg, db.NewMemoryStore(testCfg))
+	testClient.SetSignerKey(api.NewRandomSignerKey(), api.AllSigners)
+	if err := testClient.SetSigner(testSigner); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+
+	testServer.Close()
+	os.Exit(code)
+}
+
+// snapshotMsg asks the harness to send the current view on the channel.
+type snapshotMsg chan string
+
+// harness wraps the app so views can be captured from the program's
+// goroutine while it is running.
+type harness struct {
+	app tea.Model
+}
+
+func (h *harness) Init() tea.Cmd {
+	return h.app.Init()
+}
+
+func (h *harness) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if ch, ok := msg.(snapshotMsg); ok {
+		ch <- h.app.View()
+		return h, nil
+	}
+	m, cmd := h.app.Update(msg)
+	h.app = m
+	return h, cmd
+}
+
+func (h *harness) View() string {
+	return h.app.View()
+}
+
+type driver struct {
+	t  *testing.T
+	tm *teatest.TestModel
+}
+
+func newDriver(t *testing.T, w, h int) *driver {
+	ctx := &AppContext{signer: testSigner, pk: testSigner.PublicKey}
+	app := NewApp(testCfg, testClient, ctx, false)
+	tm := teatest.NewTestModel(t, &harness{app: app}, teatest.WithInitialTermSize(w, h))
+	return &driver{t: t, tm: tm}
+}
+
+func (d *driver) view() string {
+	d.t.Helper()
+	ch := make(snapshotMsg, 1)
+	d.tm.Send(ch)
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		d.t.Fatal("timed out waiting for view")
+	}
+	return ""
+}
+
+// settle waits until the plain text view contains every string in want and
+// has stopped changing, so pending commands have been applied. Styling is
+// stripped since glamour picks its own colors.
+func (d *driver) settle(want ...string) string {
+	d.t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	var last string
+	for time.Now().Before(deadline) {
+		v := ansi.Strip(d.view())
+		ok := v == last
+		for _, w := range want {
+			ok = ok && strings.Contains(v, w)
+		}
+		if ok {
+			return v
+		}
+		last = v
+		time.Sleep(100 * time.Millisecond)
+	}
+	d.t.Fatalf("timed out waiting for %q, last view:\n%s", want, last)
+	return ""
+}
+
+func (d *driver) press(keys ...tea.KeyType) {
+	for _, k := range keys {
+		d.tm.Send(tea.KeyMsg{Type: k})
+	}
+}
+
+// snapshot settles the view and compares it to the step's golden file.
+func (d *driver) snapshot(step string, want ...string) {
+	d.t.Helper()
+	v := d.settle(want...)
+	d.t.Run(step, func(t *testing.T) {
+		golden.RequireEqual(t, []byte(v))
+	})
+}
+
+func (d *driver) quit() {
+	if err := d.tm.Quit(); err != nil {
+		d.t.Fatal(err)
+	}
+	d.tm.WaitFinished(d.t, teatest.WithFinalTimeout(5*time.Second))
+}
+
+func TestNavigationFlow(t *testing.T) {
+	sizes := []struct{ w, h int }{{120, 40}, {160, 50}}
+	for _, size := range sizes {
+		t.Run(fmt.Sprintf("%dx%d", size.w, size.h), func(t *testing.T) {
+			testServer.Reset()
+			d := newDriver(t, size.w, size.h)
+			defer d.quit()
+
+			d.snapshot("feed", "shipped a new version", "/dev", "@tofui")
+
+			// open the most recent cast
+			d.press(tea.KeyEnter)
+			d.snapshot("cast", "cast by @alice", "congrats!", "running it now")
+
+			// reply to it
+			d.tm.Type("r")
+			d.settle("reply to @alice")
+			d.tm.Type(fmt.Sprintf("replying at %dx%d", size.w, size.h))
+			d.press(tea.KeyCtrlD)
+			d.tm.Type("y")
+			d.snapshot("reply", "reply by @tofui", fmt.Sprintf("replying at %dx%d", size.w, size.h))
+
+			// back to the parent to like it
+			d.tm.Type("t")
+			d.settle("cast by @alice")
+			d.tm.Type("l")
+			d.settle()
+			if !testServer.Liked("0x0000000000000000000000000000000000000a01", testSigner.FID) {
+				t.Fatal("expected cast to be liked")
+			}
+			d.snapshot("like")
+
+			// view the author's profile
+			d.tm.Type("p")
+			d.snapshot("profile", "profile: @alice", "building things")
+
+			// back to the cast, then its channel
+			d.press(tea.KeyEscape)
+			d.settle("cast")
+			d.tm.Type("c")
+			d.snapshot("channel", "developers building on farcaster")
+
+			// going back walks the history
+			d.press(tea.KeyEscape)
+			d.snapshot("back", "cast by @alice")
+		})
+	}
+}
+
+func TestFocusPrevHistory(t *testing.T) {
+	ctx := &AppContext{signer: testSigner, pk: testSigner.PublicKey}
+	a := NewApp(testCfg, testClient, ctx, false)
+
+	a.FocusCast()
+	a.FocusProfile()
+	a.FocusChannel()
+
+	for _, want := range []string{"profile", "cast", "feed", "feed"} {
+		a.FocusPrev()
+		if a.focused != want {
+			t.Fatalf("expected %s after going back, got %s", want, a.focused)
+		}
+	}
+}
+
+func TestCastHistory(t *testing.T) {
+	ctx := &AppContext{signer: testSigner, pk: testSigner.PublicKey}
+	a := NewApp(testCfg, testClient, ctx, false)
+	a.splash.SetActive(false)
+
+	op := &api.Cast{Hash: "0x1", Author: api.User{Username: "op"}}
+	reply := &api.Cast{Hash: "0x2", ParentHash: "0x1", Author: api.User{Username: "replier"}}
+	a.Update(SelectCastMsg{cast: op})
+	a.Update(SelectCastMsg{cast: reply})
+
+	a.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if a.focused != "cast" || a.cast.cast != op {
+		t.Fatalf("expected esc to return to the first cast, got %s showing %v", a.focused, a.cast.cast)
+	}
+	if a.navname != "cast by @op" {
+		t.Errorf("expected the first cast's nav name, got %q", a.navname)
+	}
+	a.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if a.focused != "feed" {
+		t.Errorf("expected esc to return to the feed, got %s", a.focused)
+	}
+}
+
+func TestSwitchAccount(t *testing.T) {
+	client := api.NewClient(testCfg, db.NewMemoryStore(testCfg))
+	client.SetSignerKey(api.NewRandomSignerKey(), api.AllSigners)
+	alice := &api.Signer{FID: 2, UUID: neynartest.SignerUUID, Username: "alice", PublicKey: "local"}
+	for _, s := range []*api.Signer{alice, testSigner} {
+		if err := client.SetSigner(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := &AppContext{signer: testSigner, pk: testSigner.PublicKey}
+	a := NewApp(testCfg, client, ctx, false)
+	a.sidebar.Init()
+	if len(a.sidebar.accounts) != 1 || a.sidebar.accounts[0].FID != alice.FID {
+		t.Fatalf("expected alice listed as another account, got %+v", a.sidebar.accounts)
+	}
+
+	a.FocusProfile()
+	msg := a.SwitchAccount()()
+	a.Update(msg)
+
+	if a.ctx.signer.FID != alice.FID {
+		t.Fatalf("expected alice to be active, got %s", a.ctx.signer.Username)
+	}
+	if client.GetSigner("local").FID != alice.FID {
+		t.Error("expected switch to be persisted")
+	}
+	if a.focused != "feed" || len(a.history) != 0 {
+		t.Errorf("expected switching to reset to the feed, got %s with %d history", a.focused, len(a.history))
+	}
+	if len(a.sidebar.accounts) != 1 || a.sidebar.accounts[0].FID != testSigner.FID {
+		t.Errorf("expected tofui listed as another account, got %+v", a.sidebar.accounts)
+	}
+}
+
+func TestSignerRevoked(t *testing.T) {
+	client := api.NewClient(testCfg, db.NewMemoryStore(testCfg))
+	client.SetSignerKey(api.NewRandomSignerKey(), api.AllSigners)
+	revoked := &api.Signer{FID: 2, UUID: neynartest.RevokedSignerUUID, Username: "alice", PublicKey: "local"}
+	if err := client.SetSigner(revoked); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &AppContext{signer: revoked, pk: "local"}
+	a := NewApp(testCfg, client, ctx, false)
+	a.splash.SetActive(false)
+
+	cast := &api.Cast{Hash: "0x0000000000000000000000000000000000000a01"}
+	a.Update(likeCastCmd(client, revoked, cast)())
+
+	if a.ctx.signer != nil {
+		t.Error("expected revoked account to be signed out")
+	}
+	if client.GetSigner("local") != nil {
+		t.Error("expected revoked signer to be removed")
+	}
+	if !a.splash.Active() || !a.splash.signin {
+		t.Error("expected sign in to be shown")
+	}
+}
+
+func TestSignOut(t *testing.T) {
+	client := api.NewClient(testCfg, db.NewMemoryStore(testCfg))
+	client.SetSignerKey(api.NewRandomSignerKey(), api.AllSigners)
+	alice := &api.Signer{FID: 2, UUID: neynartest.SignerUUID, Username: "alice", PublicKey: "local"}
+	for _, s := range []*api.Signer{alice, testSigner} {
+		if err := client.SetSigner(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := &AppContext{signer: testSigner, pk: "local"}
+	a := NewApp(testCfg, client, ctx, false)
+
+	// the other account takes over
+	a.Update(a.SignOut()())
+	if a.ctx.signer == nil || a.ctx.signer.FID != alice.FID {
+		t.Fatalf("expected alice to be active after signing out, got %+v", a.ctx.signer)
+	}
+
+	// signing out of the last account shows sign in
+	a.splash.SetActive(false)
+	a.SignOut()
+	if a.ctx.signer != nil || client.GetSigner("local") != nil {
+		t.Error("expected no account after signing out of the last one")
+	}
+	if !a.splash.Active() || !a.splash.signin {
+		t.Error("expected sign in to be shown")
+	}
+}
+
+func TestDeviceSignin(t *testing.T) {
+	client := api.NewClient(testCfg, db.NewMemoryStore(testCfg))
+	client.SetSignerKey(api.NewRandomSignerKey(), api.AllSigners)
+	a := NewApp(testCfg, client, &AppContext{pk: "local"}, false)
+	a.nonces = auth.NewNonces(time.Minute)
+	a.Update(tea.WindowSizeMsg{Width: 120, Height: 60})
+	a.splash.ShowSignin(true)
+
+	a.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	nonce, code := a.splash.nonce, a.splash.code
+	if nonce == "" || !strings.Contains(a.splash.info.View(), code) {
+		t.Fatalf("expected device code to be shown, got %q", a.splash.info.View())
+	}
+	if _, cmd := a.Update(signinPollMsg{nonce: nonce}); cmd == nil {
+		t.Fatal("expected to keep polling while sign in is pending")
+	}
+
+	// what the sign in callback does once the signer is verified
+	alice := &api.Signer{FID: 2, UUID: neynartest.SignerUUID, Username: "alice", PublicKey: "local"}
+	if _, ok := a.nonces.Consume(nonce); !ok {
+		t.Fatal("expected nonce to be usable")
+	}
+	if err := client.SetSigner(alice); err != nil {
+		t.Fatal(err)
+	}
+	a.nonces.Complete(nonce, alice.FID)
+
+	_, cmd := a.Update(signinPollMsg{nonce: nonce})
+	if cmd == nil {
+		t.Fatal("expected the poll to pick up the finished sign in")
+	}
+	a.Update(cmd())
+	if a.ctx.signer == nil || a.ctx.signer.FID != alice.FID {
+		t.Fatalf("expected alice to be signed in, got %+v", a.ctx.signer)
+	}
+	if a.splash.signin {
+		t.Error("expected sign in to be hidden")
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	a := NewApp(testCfg, testClient, &AppContext{signer: testSigner, pk: "local"}, false)
+	a.idleTimeout = 50 * time.Millisecond
+	if a.watchIdle() == nil {
+		t.Fatal("expected idle check to start")
+	}
+	if a.watchIdle() != nil {
+		t.Error("expected idle check to start once")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	a.Update(tea.KeyMsg{Type: tea.KeyDown})
+	time.Sleep(30 * time.Millisecond)
+	a.checkIdle()
+	if a.TimedOut() {
+		t.Fatal("expected input to reset the idle timer")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	cmd := a.checkIdle()
+	if !a.TimedOut() {
+		t.Fatal("expected app to time out")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("expected app to quit when idle")
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	a := NewApp(testCfg, testClient, &AppContext{signer: testSigner, pk: "local"}, false)
+	a.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	a.Update(BroadcastMsg{Text: "down for maintenance at 5pm"})
+	if !strings.Contains(a.statusLine.View(), "down for maintenance at 5pm") {
+		t.Errorf("expected notice in status line, got %q", a.statusLine.View())
+	}
+	if info := a.Info(); info.Username != testSigner.Username || info.View != "feed" {
+		t.Errorf("unexpected session info %+v", info)
+	}
+
+	a.Update(BroadcastMsg{})
+	if strings.Contains(a.statusLine.View(), "maintenance") {
+		t.Error("expected empty broadcast to clear the notice")
+	}
+}
+
+func TestSessionStyles(t *testing.T) {
+	newApp := func(p termenv.Profile) *App {
+		r := lipgloss.NewRenderer(io.Discard)
+		r.SetColorProfile(p)
+		r.SetHasDarkBackground(true)
+		return NewApp(testCfg, testClient, &AppContext{signer: testSigner, pk: "local", r: r}, false)
+	}
+	color, plain := newApp(termenv.TrueColor), newApp(termenv.Ascii)
+
+	if s := color.styles.displayName.Render("tofui"); !strings.Contains(s, "\x1b[") {
+		t.Errorf("expected the truecolor session to get colors, got %q", s)
+	}
+	if s := plain.styles.displayName.Render("tofui"); strings.Contains(s, "\x1b[") {
+		t.Errorf("expected the ascii session to get no colors, got %q", s)
+	}
+	if lipgloss.ColorProfile() != termenv.Ascii {
+		t.Error("expected sessions not to change the default renderer")
+	}
+
+	color.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	plain.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	if color.width != 120 || color.height != 40 {
+		t.Errorf("expected each session to keep its own size, got %dx%d", color.width, color.height)
+	}
+}
@@ -2,7 +2,7 @@ package ui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -11,10 +11,6 @@ import (
 	"github.com/treethought/tofui/api"
 )
 
-var style = NewStyle()
-var statsStyle = NewStyle()
-var castHeaderStyle = NewStyle().Margin(1, 1).Align(lipgloss.Top)
-
 type CastView struct {
 	app     *App
 	cast    *api.Cast
@@ -33,18 +29,18 @@ type CastView struct {
 func NewCastView(app *App, cast *api.Cast) *CastView {
 	vp := viewport.New(0, 0)
 	hp := viewport.New(0, 0)
-	hp.Style = NewStyle().BorderBottom(true).BorderStyle(lipgloss.RoundedBorder())
+	hp.Style = app.styles.New().BorderBottom(true).BorderStyle(lipgloss.RoundedBorder())
 	c := &CastView{
 		app:      app,
 		cast:     cast,
-		pfp:      NewImage(true, true, special),
-		img:      NewImage(true, true, special),
+		pfp:      NewImage(app, true, true),
+		img:      NewImage(app, true, true),
 		replies:  NewRepliesView(app),
 		vp:       &vp,
 		header:   &hp,
 		pubReply: NewPublishInput(app),
 		hasImg:   false,
-		help:     NewHelpView(app, CastViewKeyMap),
+		help:     NewHelpView(app, app.keys.Cast),
 	}
 	c.pfp.SetSize(4, 4)
 	c.help.SetFull(false)
@@ -71,7 +67,7 @@ func (m *CastView) OpenCast() tea.Cmd {
 	if m.cast == nil {
 		return nil
 	}
-	return OpenURL(fmt.Sprintf("https://warpcast.com/%s/%s", m.cast.Author.Username, m.cast.Hash))
+	return m.app.OpenURL(fmt.Sprintf("https://warpcast.com/%s/%s", m.cast.Author.Username, m.cast.Hash))
 }
 
 func (m *CastView) Reply() {
@@ -113,12 +109,20 @@ func (m *CastView) ViewParent() tea.Cmd {
 	return func() tea.Msg {
 		cast, err := m.app.client.GetCastWithReplies(m.app.ctx.signer, m.cast.ParentHash)
 		if err != nil {
-			log.Println("failed to get parent cast", err)
+			slog.Error("failed to get parent cast", "error", err)
 			return nil
 		}
 
-		return m.SetCast(cast)
+		return SelectCastMsg{cast: cast}
+	}
+}
+
+// PlayVideo plays the cast's video embed outside the terminal.
+func (m *CastView) PlayVideo() tea.Cmd {
+	if m.cast == nil || len(m.cast.Embeds) == 0 || !isVideo(m.cast.Embeds[0].URL) {
+		return nil
 	}
+	return m.app.PlayVideo(m.cast.Embeds[0].URL)
 }
 
 func (m *CastView) SetCast(cast *api.Cast) tea.Cmd {
@@ -153,9 +157,9 @@ func min(a, b int) int {
 
 func (m *CastView) resize() tea.Cmd {
 	cmds := []tea.Cmd{}
-	fx, fy := style.GetFrameSize()
-	w := min(m.w-fx, int(float64(GetWidth())*0.75))
-	h := min(m.h-fy, GetHeight()-4)
+	fx, fy := m.app.styles.cast.GetFrameSize()
+	w := min(m.w-fx, int(float64(m.app.width)*0.75))
+	h := min(m.h-fy, m.app.height-4)
 
 	m.help.SetSize(m.w, 1)
 
@@ -193,16 +197,22 @@ func (m *CastView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		_, cmd := m.pubReply.Update(msg)
 		return m, cmd
 
+	case reactMsg:
+		if m.cast != nil && m.cast.Hash == msg.hash {
+			applyReaction(m.cast, msg)
+			m.header.SetContent(m.castHeader())
+		}
+
 	case tea.KeyMsg:
 		if m.pubReply.Active() {
 			_, cmd := m.pubReply.Update(msg)
 			return m, cmd
 		}
-		if CastViewKeyMap.HandleMsg(m, msg) != nil {
-			return m, CastViewKeyMap.HandleMsg(m, msg)
+		if cmd := m.app.keys.Cast.HandleMsg(m, msg); cmd != nil {
+			return m, cmd
 		}
 	}
-	m.vp.SetContent(CastContent(m.cast, 10))
+	m.vp.SetContent(CastContent(m.app.styles, m.cast, 10))
 	m.header.SetContent(m.castHeader())
 	cmds := []tea.Cmd{}
 
@@ -222,10 +232,10 @@ func (m *CastView) castHeader() string {
 	if m.cast == nil {
 		return ""
 	}
-	return castHeaderStyle.Render(
+	return m.app.styles.castHeader.Render(
 		lipgloss.JoinVertical(lipgloss.Center,
-			UsernameHeader(&m.cast.Author, m.pfp),
-			CastStats(m.cast, 1),
+			UsernameHeader(m.app.styles, &m.cast.Author, m.pfp),
+			CastStats(m.app.styles, m.cast, 1),
 		),
 	)
 
@@ -235,8 +245,11 @@ func (m *CastView) View() string {
 	if m.pubReply.Active() {
 		return m.pubReply.View()
 	}
+	if m.pfp.Animated() {
+		m.header.SetContent(m.castHeader())
+	}
 
-	return style.Height(m.h).Render(
+	return m.app.styles.cast.Height(m.h).Render(
 		lipgloss.JoinVertical(lipgloss.Center,
 			m.header.View(),
 			m.vp.View(),
@@ -5,54 +5,22 @@ import (
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/treethought/tofui/api"
 )
 
-var (
-	subtle    = lipgloss.AdaptiveColor{Light: "#D9DCCF", Dark: "#383838"}
-	highlight = lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"}
-	special   = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"}
-
-	displayNameStyle = NewStyle().
-				MarginRight(5).
-				Foreground(highlight)
-
-	usernameStyle = NewStyle()
-
-	imgStyle = NewStyle()
-
-	headerStyle = NewStyle().BorderBottom(true)
-
-	infoStyle = NewStyle().
-			MarginLeft(5).MarginRight(5).
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderTop(true).
-			BorderForeground(subtle).AlignHorizontal(lipgloss.Center)
-
-	contentStyle = NewStyle()
-
-	md, _ = glamour.NewTermRenderer(
-		// detect background color and pick either the default dark or light theme
-		glamour.WithAutoStyle(),
-		// wrap output at specific width (default is 80)
-		glamour.WithWordWrap(80),
-	)
-)
-
-func UsernameHeader(user *api.User, img *ImageModel) string {
+func UsernameHeader(s *styles, user *api.User, img *ImageModel) string {
 	if user == nil {
 		return spinner.New().View()
 	}
-	return headerStyle.Render(lipgloss.JoinHorizontal(lipgloss.Center,
+	return s.header.Render(lipgloss.JoinHorizontal(lipgloss.Center,
 		img.View(),
 		lipgloss.JoinHorizontal(lipgloss.Top,
-			displayNameStyle.Render(
+			s.displayName.Render(
 				user.DisplayName,
 			),
-			usernameStyle.Render(
+			s.username.Render(
 				fmt.Sprintf("@%s", user.Username),
 			),
 		),
@@ -60,7 +28,7 @@ func UsernameHeader(user *api.User, img *ImageModel) string {
 	)
 }
 
-func CastStats(cast *api.Cast, margin int) string {
+func CastStats(s *styles, cast *api.Cast, margin int) string {
 	if cast == nil {
 		return spinner.New().View()
 	}
@@ -69,26 +37,26 @@ func CastStats(cast *api.Cast, margin int) string {
 		liked = EmojiLike
 	}
 	stats := lipgloss.JoinHorizontal(lipgloss.Top,
-		NewStyle().Render(fmt.Sprintf("%d ", cast.Replies.Count)),
-		NewStyle().MarginRight(margin).Render(EmojiComment),
-		NewStyle().Render(fmt.Sprintf("%d ", cast.Reactions.LikesCount)),
-		NewStyle().MarginRight(margin).Render(liked),
-		NewStyle().Render(fmt.Sprintf("%d ", cast.Reactions.RecastsCount)),
-		NewStyle().MarginRight(margin).Render(EmojiRecyle),
+		s.New().Render(fmt.Sprintf("%d ", cast.Replies.Count)),
+		s.New().MarginRight(margin).Render(EmojiComment),
+		s.New().Render(fmt.Sprintf("%d ", cast.Reactions.LikesCount)),
+		s.New().MarginRight(margin).Render(liked),
+		s.New().Render(fmt.Sprintf("%d ", cast.Reactions.RecastsCount)),
+		s.New().MarginRight(margin).Render(EmojiRecyle),
 	)
 	return stats
 
 }
 
-func CastContent(cast *api.Cast, maxHeight int, imgs ...ImageModel) string {
+func CastContent(s *styles, cast *api.Cast, maxHeight int) string {
 	if cast == nil {
 		return spinner.New().View()
 	}
-	m, err := md.Render(cast.Text)
+	m, err := s.markdown().Render(cast.Text)
 	if err != nil {
 		m = cast.Text
 	}
-	return contentStyle.MaxHeight(maxHeight).Render(m)
+	return s.content.MaxHeight(maxHeight).Render(m)
 }
 
 func getCastChannelCmd(client *api.Client, cast *api.Cast) tea.Cmd {
@@ -131,7 +99,7 @@ func NewCastFeedItem(app *App, cast *api.Cast, compact bool) (*CastFeedItem, tea
 	c := &CastFeedItem{
 		app:     app,
 		cast:    cast,
-		pfp:     NewImage(true, true, special),
+		pfp:     NewImage(app, true, true),
 		compact: compact,
 	}
 	c.pfp.SetURL(cast.Author.PfpURL, false)
@@ -191,7 +159,7 @@ func (m *CastFeedItem) AsRow(ch, stats bool) []string {
 		cols = append(cols, fmt.Sprintf("/%s", m.channel))
 	}
 	if stats {
-		cols = append(cols, CastStats(m.cast, 2))
+		cols = append(cols, CastStats(m.app.styles, m.cast, 2))
 	} else {
 	}
 	cols = append(cols, m.cast.Author.DisplayName, m.cast.Text)
@@ -199,11 +167,11 @@ func (m *CastFeedItem) AsRow(ch, stats bool) []string {
 }
 
 func (i *CastFeedItem) Title() string {
-	return UsernameHeader(&i.cast.Author, i.pfp)
+	return UsernameHeader(i.app.styles, &i.cast.Author, i.pfp)
 }
 
 func (i *CastFeedItem) Description() string {
-	return CastContent(i.cast, 3)
+	return CastContent(i.app.styles, i.cast, 3)
 }
 
 func (i *CastFeedItem) FilterValue() string {
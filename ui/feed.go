@@ -2,7 +2,7 @@ package ui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/progress"
@@ -15,10 +15,7 @@ import (
 	"github.com/treethought/tofui/api"
 )
 
-var (
-	feedStyle          = NewStyle().Margin(2, 2).Align(lipgloss.Center)
-	channelHeaderStyle = NewStyle().Margin(1, 1).Align(lipgloss.Top).Border(lipgloss.RoundedBorder())
-)
+var ()
 
 type feedType string
 
@@ -55,6 +52,7 @@ type reactMsg struct {
 type FeedView struct {
 	app     *App
 	table   table.Model
+	styles  *styles
 	items   []*CastFeedItem
 	loading *Loading
 	req     *api.FeedRequest
@@ -68,26 +66,26 @@ type FeedView struct {
 	w, h        int
 }
 
-func getTableStyles() table.Styles {
+func getTableStyles(st *styles) table.Styles {
 	s := table.DefaultStyles()
-	s.Header = NewStyle().Bold(true).Padding(0, 1).
+	s.Header = st.New().Bold(true).Padding(0, 1).
 		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(lipgloss.Color("240")).
+		BorderForeground(st.theme.HeaderBorder).
 		BorderBottom(true).
 		Bold(false)
-	s.Selected = NewStyle().Bold(true).
-		Foreground(lipgloss.Color("229")).
-		Background(lipgloss.Color("57")).
+	s.Selected = st.New().Bold(true).
+		Foreground(st.theme.SelectedFg).
+		Background(st.theme.SelectedBg).
 		Bold(false)
 
-	s.Cell = NewStyle().Padding(0, 1)
+	s.Cell = st.New().Padding(0, 1)
 	return s
 
 }
 
-func newTable() table.Model {
+func newTable(s *styles) table.Model {
 
-	t := table.New(
+	tbl := table.New(
 		table.WithFocused(true),
 		table.WithKeyMap(table.KeyMap{
 			LineUp:     key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "up")),
@@ -98,9 +96,8 @@ func newTable() table.Model {
 			GotoBottom: key.NewBinding(key.WithKeys("end", "G"), key.WithHelp("End/G", "go to bottom")),
 		}),
 	)
-	s := getTableStyles()
-	t.SetStyles(s)
-	return t
+	tbl.SetStyles(getTableStyles(s))
+	return tbl
 }
 
 func NewFeedView(app *App, ft feedType) *FeedView {
@@ -110,20 +107,21 @@ func NewFeedView(app *App, ft feedType) *FeedView {
 
 	return &FeedView{
 		app:         app,
-		table:       newTable(),
+		table:       newTable(app.styles),
+		styles:      app.styles,
 		items:       []*CastFeedItem{},
-		loading:     NewLoading(),
+		loading:     NewLoading(app.styles),
 		showChannel: true,
 		showStats:   true,
 		descVp:      &dvp,
-		headerImg:   NewImage(true, true, special),
+		headerImg:   NewImage(app, true, true),
 		feedType:    ft,
 	}
 }
 
 func (m *FeedView) SetDescription(desc string) {
 	if m.feedType != feedTypeChannel {
-		log.Println("not setting description: type: ", m.feedType)
+		slog.Debug("not setting description", "feed_type", m.feedType)
 		return
 	}
 	m.description = desc
@@ -141,7 +139,7 @@ func (m *FeedView) SetShowStats(show bool) {
 }
 
 func (m *FeedView) setTableConfig() {
-	fx, _ := feedStyle.GetFrameSize()
+	fx, _ := m.app.styles.feed.GetFrameSize()
 	w := m.table.Width() - fx //- 10
 
 	if !m.showChannel && !m.showStats {
@@ -187,14 +185,32 @@ func (m *FeedView) Clear() {
 
 func likeCastCmd(client *api.Client, signer *api.Signer, cast *api.Cast) tea.Cmd {
 	return func() tea.Msg {
-		log.Println("liking cast", cast.Hash)
-		if err := client.React(signer, cast.Hash, "like"); err != nil {
+		slog.Debug("liking cast", "cast", cast.Hash)
+		err := client.React(signer, cast.Hash, "like")
+		if api.IsSignerRevoked(err) {
+			return &signerRevokedMsg{signer: signer}
+		}
+		if err != nil {
 			return apiErrorMsg{err}
 		}
 		return reactMsg{hash: cast.Hash, rtype: "like", state: true}
 	}
 }
 
+// applyReaction updates the cast's viewer context and counts after the
+// viewer reacted to it.
+func applyReaction(cast *api.Cast, msg reactMsg) {
+	if msg.rtype != "like" || cast.ViewerContext.Liked == msg.state {
+		return
+	}
+	cast.ViewerContext.Liked = msg.state
+	if msg.state {
+		cast.Reactions.LikesCount++
+	} else if cast.Reactions.LikesCount > 0 {
+		cast.Reactions.LikesCount--
+	}
+}
+
 func getDefaultFeedCmd(client *api.Client, signer *api.Signer) tea.Cmd {
 	if signer == nil {
 		return nil
@@ -213,7 +229,7 @@ func getFeedCmd(client *api.Client, req *api.FeedRequest) tea.Cmd {
 		}
 		feed, err := client.GetFeed(req)
 		if err != nil {
-			log.Println("feedview error getting feed", err)
+			slog.Error("feedview error getting feed", "error", err)
 			return err
 		}
 		return feed
@@ -222,7 +238,7 @@ func getFeedCmd(client *api.Client, req *api.FeedRequest) tea.Cmd {
 
 func getChannelFeedCmd(client *api.Client, pu string) tea.Cmd {
 	return func() tea.Msg {
-		log.Println("getting channel feed")
+		slog.Debug("getting channel feed")
 		req := &api.FeedRequest{
 			FeedType: "filter", FilterType: "parent_url",
 			ParentURL: pu, Limit: 100,
@@ -322,14 +338,14 @@ func (m *FeedView) SetSize(w, h int) {
 			dy = dmin
 		}
 		m.headerImg.SetSize(4, 4)
-		fx, fy := channelHeaderStyle.GetFrameSize()
+		fx, fy := m.app.styles.channelHeader.GetFrameSize()
 		m.descVp.Width = w - fx - 4
 		m.descVp.Height = dy - fy
 	}
 
-	_, dy := lipgloss.Size(channelHeaderStyle.Render(m.descVp.View()))
-	fx, fy := feedStyle.GetFrameSize()
-	x := min(w-fx, int(float64(GetWidth())*0.75))
+	_, dy := lipgloss.Size(m.app.styles.channelHeader.Render(m.descVp.View()))
+	fx, fy := m.app.styles.feed.GetFrameSize()
+	x := min(w-fx, int(float64(m.app.width)*0.75))
 	m.table.SetWidth(x)
 	m.table.SetHeight(h - fy - dy)
 
@@ -353,7 +369,7 @@ func (m *FeedView) OpenCurrentItem() tea.Cmd {
 	if current == nil {
 		return nil
 	}
-	return OpenURL(fmt.Sprintf("https://warpcast.com/%s/%s", current.cast.Author.Username, current.cast.Hash))
+	return m.app.OpenURL(fmt.Sprintf("https://warpcast.com/%s/%s", current.cast.Author.Username, current.cast.Hash))
 }
 func (m *FeedView) ViewCurrentProfile() tea.Cmd {
 	current := m.getCurrentItem()
@@ -371,7 +387,7 @@ func (m *FeedView) ViewCurrentProfile() tea.Cmd {
 
 func fetchChannelCmd(client *api.Client, pu string) tea.Cmd {
 	return func() tea.Msg {
-		log.Println("fetching channel obj")
+		slog.Debug("fetching channel obj")
 		c, err := client.GetChannelByParentUrl(pu)
 		return &fetchChannelMsg{pu, c, err}
 	}
@@ -386,7 +402,7 @@ func (m *FeedView) ViewCurrentChannel() tea.Cmd {
 		return nil
 	}
 	if m.feedType == feedTypeChannel {
-		log.Println("already viewing channel")
+		slog.Debug("already viewing channel")
 		return nil
 	}
 	m.loading.SetActive(true)
@@ -415,7 +431,7 @@ func (m *FeedView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds = append(cmds, cmd)
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if cmd := FeedKeyMap.HandleMsg(m, msg); cmd != nil {
+		if cmd := m.app.keys.Feed.HandleMsg(m, msg); cmd != nil {
 			return m, cmd
 		}
 
@@ -427,7 +443,7 @@ func (m *FeedView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.setItems(msg.Casts)
 	case *channelFeedMsg:
 		if msg.err != nil {
-			log.Println("channel feed error", msg.err)
+			m.app.logger().Error("failed to load channel feed", "error", msg.err)
 			return m, nil
 		}
 		m.Clear()
@@ -442,22 +458,18 @@ func (m *FeedView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.err != nil {
 			return m, nil
 		}
-		m.SetDescription(channelDescription(msg.channel, m.headerImg))
+		m.SetDescription(channelDescription(m.app.styles, msg.channel, m.headerImg))
 		m.headerImg.SetURL(msg.channel.ImageURL, false)
 		return m, m.headerImg.Render()
 
 	case reactMsg:
-		current := m.getCurrentItem()
-		if current == nil || current.cast == nil {
-			return m, nil
-		}
-
-		if current.cast.Hash != msg.hash {
-			return m, m.SetDefaultParams()
-		}
-		if msg.rtype == "like" && msg.state {
-			current.cast.ViewerContext.Liked = true
+		for _, item := range m.items {
+			if item.cast != nil && item.cast.Hash == msg.hash {
+				applyReaction(item.cast, msg)
+			}
 		}
+		m.populateItems()
+		return m, nil
 
 	case tea.WindowSizeMsg:
 		m.SetSize(msg.Width, msg.Height)
@@ -472,7 +484,7 @@ func (m *FeedView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		ni, cmd := c.Update(msg)
 		ci, ok := ni.(*CastFeedItem)
 		if !ok {
-			log.Println("failed to cast to CastFeedItem")
+			slog.Debug("failed to cast to CastFeedItem")
 		}
 		newItems = append(newItems, ci)
 
@@ -493,49 +505,54 @@ func (m *FeedView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *FeedView) View() string {
+	if m.styles != m.app.styles {
+		m.styles = m.app.styles
+		m.table.SetStyles(getTableStyles(m.styles))
+		m.loading.SetStyles(m.styles)
+	}
 	if m.loading.IsActive() {
 		return m.loading.View()
 	}
 	if m.feedType == feedTypeChannel {
 		return lipgloss.JoinVertical(lipgloss.Top,
-			channelHeaderStyle.Render(m.descVp.View()),
-			feedStyle.Render(m.table.View()),
+			m.styles.channelHeader.Render(m.descVp.View()),
+			m.styles.feed.Render(m.table.View()),
 		)
 	}
 
-	return feedStyle.Render(m.table.View())
+	return m.styles.feed.Render(m.table.View())
 
 }
 
-func channelStats(c *api.Channel, margin int) string {
+func channelStats(s *styles, c *api.Channel, margin int) string {
 	if c == nil {
 		return spinner.New().View()
 	}
 	stats := lipgloss.JoinHorizontal(lipgloss.Top,
-		NewStyle().Render(fmt.Sprintf("/%s ", c.ID)),
-		NewStyle().MarginRight(margin).Render(EmojiPerson),
-		NewStyle().Render(fmt.Sprintf("%d ", c.FollowerCount)),
-		NewStyle().MarginRight(margin).Render("followers"),
-		// NewStyle().Render(fmt.Sprintf("%d ", c.Object
-		// NewStyle().MarginRight(margin).Render(EmojiRecyle),
+		s.New().Render(fmt.Sprintf("/%s ", c.ID)),
+		s.New().MarginRight(margin).Render(EmojiPerson),
+		s.New().Render(fmt.Sprintf("%d ", c.FollowerCount)),
+		s.New().MarginRight(margin).Render("followers"),
+		// s.New().Render(fmt.Sprintf("%d ", c.Object
+		// s.New().MarginRight(margin).Render(EmojiRecyle),
 	)
 	return stats
 }
 
-func channelHeader(c *api.Channel, img *ImageModel) string {
-	return headerStyle.Render(lipgloss.JoinHorizontal(lipgloss.Center,
+func channelHeader(s *styles, c *api.Channel, img *ImageModel) string {
+	return s.header.Render(lipgloss.JoinHorizontal(lipgloss.Center,
 		img.View(),
 		lipgloss.JoinVertical(lipgloss.Top,
-			displayNameStyle.Render(c.Name),
+			s.displayName.Render(c.Name),
 			c.Description,
 		),
 	),
 	)
 }
 
-func channelDescription(c *api.Channel, img *ImageModel) string {
+func channelDescription(s *styles, c *api.Channel, img *ImageModel) string {
 	return lipgloss.JoinVertical(lipgloss.Bottom,
-		channelHeader(c, img),
-		NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderBottom(true).Padding(0).Render(channelStats(c, 1)),
+		channelHeader(s, c, img),
+		s.New().BorderStyle(lipgloss.NormalBorder()).BorderBottom(true).Padding(0).Render(channelStats(s, c, 1)),
 	)
 }
@@ -0,0 +1,283 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/disintegration/imaging"
+)
+
+// graphics is the protocol images are drawn with.
+type graphics string
+
+const (
+	graphicsHalfblocks graphics = "halfblocks"
+	graphicsKitty      graphics = "kitty"
+	graphicsITerm2     graphics = "iterm2"
+	graphicsSixel      graphics = "sixel"
+)
+
+// cellWidth and cellHeight are the pixel size assumed for a terminal cell
+// when sizing images, as terminals aren't asked for theirs.
+const (
+	cellWidth  = 10
+	cellHeight = 20
+)
+
+// newGraphics picks the protocol for a session. protocol is the configured
+// images.protocol and env looks up the terminal's environment, nil when it
+// isn't known.
+func newGraphics(protocol string, env func(string) string) graphics {
+	switch protocol {
+	case "", "auto":
+		if env == nil {
+			return graphicsHalfblocks
+		}
+		return detectGraphics(env)
+	}
+	return graphics(protocol)
+}
+
+// detectGraphics guesses what the terminal supports from TERM and the
+// variables terminals set, falling back to half blocks. Multiplexers are
+// left on half blocks as they only pass graphics through when configured
+// to.
+func detectGraphics(env func(string) string) graphics {
+	term, program := env("TERM"), env("TERM_PROGRAM")
+	switch {
+	case env("TMUX") != "" || strings.HasPrefix(term, "tmux") || strings.HasPrefix(term, "screen"):
+		return graphicsHalfblocks
+	case strings.Contains(term, "kitty"), env("KITTY_WINDOW_ID") != "",
+		strings.Contains(term, "ghostty"), program == "ghostty":
+		return graphicsKitty
+	case program == "iTerm.app", env("LC_TERMINAL") == "iTerm2", program == "WezTerm":
+		return graphicsITerm2
+	case strings.HasPrefix(term, "foot"), strings.HasPrefix(term, "mlterm"),
+		strings.Contains(term, "sixel"), program == "contour":
+		return graphicsSixel
+	}
+	return graphicsHalfblocks
+}
+
+// sessionEnv looks variables up in what the SSH client sent, with TERM
+// taken from the pty request.
+func sessionEnv(s ssh.Session) func(string) string {
+	env := map[string]string{}
+	for _, kv := range s.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	if pty, _, ok := s.Pty(); ok && pty.Term != "" {
+		env["TERM"] = pty.Term
+	}
+	return func(k string) string { return env[k] }
+}
+
+// fitCells sizes img to at most cols by rows cells keeping its aspect
+// ratio. rows of 0 leaves the height unbounded.
+func fitCells(img image.Image, cols, rows int) (int, int) {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 || cols <= 0 {
+		return 0, 0
+	}
+	h := (cols*cellWidth*b.Dy()/b.Dx() + cellHeight/2) / cellHeight
+	if rows > 0 && h > rows {
+		h = rows
+		cols = (h*cellHeight*b.Dx()/b.Dy() + cellWidth/2) / cellWidth
+	}
+	return max(cols, 1), max(h, 1)
+}
+
+// encodeImage draws img in at most cols by rows cells with g. key names the
+// image so kitty can tell images apart.
+func encodeImage(g graphics, img image.Image, cols, rows int, key string) (string, error) {
+	cols, rows = fitCells(img, cols, rows)
+	if cols == 0 {
+		return "", fmt.Errorf("image is empty")
+	}
+	img = imaging.Resize(img, cols*cellWidth, rows*cellHeight, imaging.Lanczos)
+	switch g {
+	case graphicsKitty:
+		return kittyImage(img, cols, rows, key)
+	case graphicsITerm2:
+		return rowStrips(img, cols, rows, iterm2Image)
+	case graphicsSixel:
+		return rowStrips(img, cols, rows, func(strip image.Image, cols int) (string, error) {
+			return sixel(strip), nil
+		})
+	}
+	return "", fmt.Errorf("unknown image protocol %q", g)
+}
+
+// rowStrips draws img one row of cells at a time. Each line carries its own
+// slice of the image, so redrawing any one line of the view redraws that
+// part of the image too. The slice is drawn over the line's spaces with
+// the cursor saved and restored around it.
+func rowStrips(img image.Image, cols, rows int, draw func(image.Image, int) (string, error)) (string, error) {
+	lines := make([]string, rows)
+	b := img.Bounds()
+	for y := 0; y < rows; y++ {
+		strip := imaging.Crop(img, image.Rect(b.Min.X, b.Min.Y+y*cellHeight, b.Max.X, b.Min.Y+(y+1)*cellHeight))
+		seq, err := draw(strip, cols)
+		if err != nil {
+			return "", err
+		}
+		lines[y] = fmt.Sprintf("%s\x1b[%dD\x1b7%s\x1b8\x1b[%dC", strings.Repeat(" ", cols), cols, seq, cols)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func iterm2Image(img image.Image, cols int) (string, error) {
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=1;preserveAspectRatio=0:%s\a",
+		len(data), cols, base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// kittyDiacritics mark the row of a kitty placeholder cell, in the order
+// kitty numbers them. Images are limited to this many rows.
+var kittyDiacritics = []rune{
+	0x0305, 0x030D, 0x030E, 0x0310, 0x0312, 0x033D, 0x033E, 0x033F,
+	0x0346, 0x034A, 0x034B, 0x034C, 0x0350, 0x0351, 0x0352, 0x0357,
+	0x035B, 0x0363, 0x0364, 0x0365, 0x0366, 0x0367, 0x0368, 0x0369,
+	0x036A, 0x036B, 0x036C, 0x036D, 0x036E, 0x036F, 0x0483, 0x0484,
+	0x0485, 0x0486, 0x0487,
+}
+
+const kittyPlaceholder = '\U0010EEEE'
+
+// kittyImage transmits img as a virtual placement and draws it with
+// unicode placeholders. The placeholders are ordinary text to the rest of
+// the ui, so the image moves and disappears with the cells it's drawn in.
+// The image id is carried in the placeholders' foreground color.
+func kittyImage(img image.Image, cols, rows int, key string) (string, error) {
+	if rows > len(kittyDiacritics) {
+		cols = max(1, cols*len(kittyDiacritics)/rows)
+		rows = len(kittyDiacritics)
+	}
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%dx%d", key, cols, rows)
+	id := h.Sum32() & 0xffffff
+	if id == 0 {
+		id = 1
+	}
+
+	var b strings.Builder
+	payload := base64.StdEncoding.EncodeToString(data)
+	for i := 0; i < len(payload); i += 4096 {
+		chunk := payload[i:min(i+4096, len(payload))]
+		more := 0
+		if i+4096 < len(payload) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,U=1,f=100,q=2,i=%d,c=%d,r=%d,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	lines := make([]string, rows)
+	for y := range lines {
+		var l strings.Builder
+		if y == 0 {
+			l.WriteString(b.String())
+		}
+		fmt.Fprintf(&l, "\x1b[38;2;%d;%d;%dm", id>>16&0xff, id>>8&0xff, id&0xff)
+		// the rest of the row follows on from the first cell
+		l.WriteRune(kittyPlaceholder)
+		l.WriteRune(kittyDiacritics[y])
+		l.WriteRune(kittyDiacritics[0])
+		l.WriteString(strings.Repeat(string(kittyPlaceholder), cols-1))
+		l.WriteString("\x1b[39m")
+		lines[y] = l.String()
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// sixel encodes img as a sixel image, quantized to 256 colors. Transparent
+// pixels are left as they are.
+func sixel(img image.Image) string {
+	b := img.Bounds()
+	p := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), append(color.Palette{color.Transparent}, palette.Plan9[:255]...))
+	draw.FloydSteinberg.Draw(p, p.Bounds(), img, b.Min)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "\x1bP0;1;0q\"1;1;%d;%d", p.Rect.Dx(), p.Rect.Dy())
+	for i, c := range p.Palette[1:] {
+		r, g, bl, _ := c.RGBA()
+		fmt.Fprintf(&out, "#%d;2;%d;%d;%d", i+1, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+	}
+
+	w, h := p.Rect.Dx(), p.Rect.Dy()
+	bits := make([]byte, w)
+	for band := 0; band < h; band += 6 {
+		used := map[uint8]bool{}
+		for y := band; y < min(band+6, h); y++ {
+			for x := 0; x < w; x++ {
+				if i := p.ColorIndexAt(x, y); i != 0 {
+					used[i] = true
+				}
+			}
+		}
+		for i := 1; i < len(p.Palette); i++ {
+			if !used[uint8(i)] {
+				continue
+			}
+			for x := range bits {
+				bits[x] = 0
+				for dy := 0; dy < 6 && band+dy < h; dy++ {
+					if p.ColorIndexAt(x, band+dy) == uint8(i) {
+						bits[x] |= 1 << dy
+					}
+				}
+			}
+			fmt.Fprintf(&out, "#%d", i)
+			writeSixelRuns(&out, bits)
+			out.WriteByte('$')
+		}
+		out.WriteByte('-')
+	}
+	out.WriteString("\x1b\\")
+	return out.String()
+}
+
+// writeSixelRuns writes one color's row of sixels, run length encoded.
+func writeSixelRuns(out *strings.Builder, bits []byte) {
+	for x := 0; x < len(bits); {
+		n := 1
+		for x+n < len(bits) && bits[x+n] == bits[x] {
+			n++
+		}
+		c := byte(63 + bits[x])
+		if n > 3 {
+			fmt.Fprintf(out, "!%d%c", n, c)
+		} else {
+			out.WriteString(strings.Repeat(string(c), n))
+		}
+		x += n
+	}
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"image"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestGraphics(t *testing.T) {
+	for _, tc := range []struct {
+		env  map[string]string
+		want graphics
+	}{
+		{map[string]string{"TERM": "xterm-kitty"}, graphicsKitty},
+		{map[string]string{"TERM": "xterm-256color", "LC_TERMINAL": "iTerm2"}, graphicsITerm2},
+		{map[string]string{"TERM": "foot"}, graphicsSixel},
+		{map[string]string{"TERM": "screen-256color", "KITTY_WINDOW_ID": "1"}, graphicsHalfblocks},
+		{map[string]string{"TERM": "xterm-256color"}, graphicsHalfblocks},
+	} {
+		env := func(k string) string { return tc.env[k] }
+		if got := newGraphics("auto", env); got != tc.want {
+			t.Errorf("expected %v for %v, got %s", tc.want, tc.env, got)
+		}
+	}
+	if got := newGraphics("sixel", nil); got != graphicsSixel {
+		t.Errorf("expected the configured protocol to win, got %s", got)
+	}
+	if got := newGraphics("auto", nil); got != graphicsHalfblocks {
+		t.Errorf("expected half blocks for an unknown terminal, got %s", got)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for _, g := range []graphics{graphicsKitty, graphicsITerm2, graphicsSixel} {
+		s, err := encodeImage(g, img, 4, 4, "https://example.com/pfp.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a square image is half as tall as it is wide in cells
+		if w, h := lipgloss.Width(s), lipgloss.Height(s); w != 4 || h != 2 {
+			t.Errorf("expected %s to take 4x2 cells, got %dx%d", g, w, h)
+		}
+	}
+}
@@ -20,10 +20,17 @@ type HelpView struct {
 	km   keymap
 }
 
+func newHelp(app *App) help.Model {
+	if app == nil {
+		return help.New()
+	}
+	return app.styles.newHelp()
+}
+
 func NewHelpView(app *App, km keymap) *HelpView {
 	return &HelpView{
 		app: app,
-		h:   help.New(),
+		h:   newHelp(app),
 		vp:  viewport.Model{},
 		km:  km,
 	}
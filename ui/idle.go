@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type idleCheckMsg struct{}
+
+func idleCheck(after time.Duration) tea.Cmd {
+	return tea.Tick(after, func(time.Time) tea.Msg {
+		return idleCheckMsg{}
+	})
+}
+
+// watchIdle starts checking for inactivity when the app has an idle timeout.
+func (a *App) watchIdle() tea.Cmd {
+	if a.idleTimeout == 0 || a.watchingIdle {
+		return nil
+	}
+	a.watchingIdle = true
+	a.lastInput = time.Now()
+	return idleCheck(a.idleTimeout)
+}
+
+func (a *App) checkIdle() tea.Cmd {
+	idle := time.Since(a.lastInput)
+	if idle >= a.idleTimeout {
+		a.timedOut = true
+		return tea.Quit
+	}
+	return idleCheck(a.idleTimeout - idle)
+}
+
+// TimedOut reports whether the app quit because it was idle.
+func (a *App) TimedOut() bool {
+	return a.timedOut
+}
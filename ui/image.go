@@ -8,12 +8,13 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
-	_ "image/gif"
+	"image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
@@ -23,11 +24,14 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/disintegration/imaging"
 	"github.com/lucasb-eyer/go-colorful"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
 	_ "golang.org/x/image/bmp"
 	_ "golang.org/x/image/tiff"
 	_ "golang.org/x/image/webp"
 
 	"github.com/treethought/tofui/db"
+	"github.com/treethought/tofui/metrics"
 )
 
 type imageDownloadMsg struct {
@@ -37,6 +41,10 @@ type imageDownloadMsg struct {
 type convertImageToStringMsg struct {
 	url string
 	str string
+	// frames and delays are set when the image is animated, str being the
+	// first frame
+	frames []string
+	delays []time.Duration
 }
 
 type downloadError struct {
@@ -54,7 +62,7 @@ const (
 )
 
 // ToString converts an image to a string representation of an image.
-func ToString(width int, img image.Image) string {
+func ToString(r *lipgloss.Renderer, width int, img image.Image) string {
 	img = imaging.Resize(img, width, 0, imaging.Lanczos)
 	b := img.Bounds()
 	imageWidth := b.Max.X
@@ -71,7 +79,7 @@ func ToString(width int, img image.Image) string {
 			color1 := lipgloss.Color(c1.Hex())
 			c2, _ := colorful.MakeColor(img.At(x, heightCounter+1))
 			color2 := lipgloss.Color(c2.Hex())
-			str.WriteString(NewStyle().Foreground(color1).
+			str.WriteString(r.NewStyle().Foreground(color1).
 				Background(color2).Render("▀"))
 		}
 
@@ -87,22 +95,33 @@ type embedPreview struct {
 	ImageURL    string
 }
 
-func getEmbedPreview(url string) (*embedPreview, error) {
-	if cached, err := db.GetDB().Get([]byte(fmt.Sprintf("embed:%s", url))); err == nil {
+func getEmbedPreview(store db.Store, url string) (*embedPreview, error) {
+	key := []byte(fmt.Sprintf("embed:%s", url))
+	if cached, err := store.Get(key); err == nil {
 		p := &embedPreview{}
-		if err := json.Unmarshal(cached, p); err != nil {
+		err := json.Unmarshal(cached, p)
+		if err == nil {
 			return p, nil
 		}
+		slog.Error("dropping corrupt cached embed", "error", err)
+		_ = store.Delete(key)
 	}
-	resp, err := http.Get(url)
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	// the meta tags are in the head, the rest of a large page isn't needed
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, maxEmbedPageBytes))
 	if err != nil {
-		log.Println("failed getting document", url, err)
+		slog.Error("failed getting document", "url", url, "error", err)
 		return nil, err
 	}
 
@@ -124,8 +143,8 @@ func getEmbedPreview(url string) (*embedPreview, error) {
 	}
 	if preview.ImageURL != "" {
 		if d, err := json.Marshal(preview); err == nil {
-			if err := db.GetDB().Set([]byte(fmt.Sprintf("embed:%s", url)), d); err != nil {
-				log.Println("error caching embed", err)
+			if err := store.Set(key, d); err != nil {
+				slog.Error("error caching embed", "error", err)
 				return preview, nil
 			}
 		}
@@ -134,33 +153,65 @@ func getEmbedPreview(url string) (*embedPreview, error) {
 	return preview, nil
 }
 
-func getImageCmd(width int, url string, embed bool) tea.Cmd {
+// defaultImageFetches is the number of images a session downloads at once
+// when not configured.
+const defaultImageFetches = 8
+
+// getImageCmd fetches the image, waiting for a slot in fetches first so a
+// session can't start an unbounded number of downloads.
+func getImageCmd(r *lipgloss.Renderer, g graphics, store db.Store, fetches chan struct{}, width, height int, url string, embed bool) tea.Cmd {
 	return func() tea.Msg {
-		data, err := getImage(width, url, embed)
+		if fetches != nil {
+			fetches <- struct{}{}
+			defer func() { <-fetches }()
+		}
+		var data []byte
+		var err error
+		if isVideo(url) {
+			data, err = videoPoster(store, url)
+			if err != nil {
+				slog.Debug("no poster for video", "url", url, "error", err)
+				return convertImageToStringMsg{url: url, str: videoPlaceholder}
+			}
+		} else {
+			data, err = getImage(store, width, url, embed)
+		}
 		if err != nil {
+			metrics.ImageFetchFailures.WithLabelValues("download").Inc()
 			return downloadError{err: err, url: url}
 		}
-		imgString, err := convertImageToString(width, data)
+		frames, delays, err := convertImageToString(r, g, width, height, url, data)
 		if err != nil {
+			metrics.ImageFetchFailures.WithLabelValues("decode").Inc()
 			return decodeError{err: err, url: url}
 		}
-		return convertImageToStringMsg{url: url, str: imgString}
+		msg := convertImageToStringMsg{url: url, str: frames[0]}
+		if len(frames) > 1 {
+			msg.frames, msg.delays = frames, delays
+		}
+		return msg
 	}
 }
 
-func getImage(width int, url string, embed bool) ([]byte, error) {
-	if strings.HasSuffix(url, ".gif") || strings.HasSuffix(url, ".svg") {
-		return nil, fmt.Errorf("gif not supported")
+// isImageURL reports whether url names an image file, which needs no
+// preview page fetched to find its image.
+func isImageURL(url string) bool {
+	switch strings.ToLower(path.Ext(strings.SplitN(url, "?", 2)[0])) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg", ".bmp", ".tiff":
+		return true
 	}
+	return false
+}
 
-	if embed {
-		ep, err := getEmbedPreview(url)
+func getImage(store db.Store, width int, url string, embed bool) ([]byte, error) {
+	if embed && !isImageURL(url) {
+		ep, err := getEmbedPreview(store, url)
 		if err == nil && ep.ImageURL != "" {
 			url = ep.ImageURL
 		}
 	}
 
-	cached, err := db.GetDB().Get([]byte(fmt.Sprintf("img:%s", url)))
+	cached, err := store.Get([]byte(fmt.Sprintf("img:%s", url)))
 	if err == nil {
 		return cached, nil
 	}
@@ -184,51 +235,253 @@ func getImage(width int, url string, embed bool) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != 200 {
 		return nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
 	}
-	d, err := io.ReadAll(resp.Body)
+	d, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
 	if err != nil {
 		return nil, err
 	}
-	if err := db.GetDB().Set([]byte(fmt.Sprintf("img:%s", url)), d); err != nil {
-		log.Println("error saving image", err)
+	if len(d) > maxImageBytes {
+		return nil, fmt.Errorf("image is larger than %dMB", maxImageBytes>>20)
+	}
+	if err := store.Set([]byte(fmt.Sprintf("img:%s", url)), d); err != nil {
+		slog.Error("error saving image", "error", err)
 	}
 	return d, nil
 }
 
-func convertImageToString(width int, ib []byte) (string, error) {
-	ir := bytes.NewReader(ib)
+// convertImageToString draws each frame of the image width cells wide. Half
+// blocks use the image's height, other protocols fit it within height rows
+// as well. Animations stop early when their frames outgrow maxAnimBytes.
+func convertImageToString(r *lipgloss.Renderer, g graphics, width, height int, url string, ib []byte) ([]string, []time.Duration, error) {
+	imgs, delays, err := decodeFrames(ib)
+	if err != nil {
+		return nil, nil, err
+	}
+	var frames []string
+	size := 0
+	for i, img := range imgs {
+		// Check if the decoded image is of type NRGBA (non-alpha-premultiplied color)
+		// If it's not, convert it to NRGBA
+		// needed for bubbletea
+		if _, ok := img.(*image.NRGBA); !ok {
+			rgba := image.NewNRGBA(img.Bounds())
+			draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+			img = rgba
+		}
+
+		var str string
+		if g == graphicsHalfblocks {
+			str = ToString(r, width, img)
+		} else {
+			// each frame is its own image to kitty
+			str, err = encodeImage(g, img, width, height, fmt.Sprintf("%s#%d", url, i))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		size += len(str)
+		if i > 0 && size > maxAnimBytes {
+			slog.Debug("animation cut short", "url", url, "frames", i, "of", len(imgs))
+			break
+		}
+		frames = append(frames, str)
+	}
+	return frames, delays[:len(frames)], nil
+}
+
+const (
+	// maxImageBytes is the largest image downloaded
+	maxImageBytes = 16 << 20
+	// maxEmbedPageBytes is how much of a page is read for its preview
+	maxEmbedPageBytes = 1 << 20
+	// maxAnimFrames is the most frames decoded from an animation, longer
+	// ones stop after it
+	maxAnimFrames = 64
+	// maxAnimPixels bounds the pixels of all decoded frames, so large
+	// animations keep fewer frames
+	maxAnimPixels = 16 << 20
+	// maxAnimBytes bounds the drawn frames of one animation
+	maxAnimBytes = 4 << 20
+	// svgSize is the longest side svgs are rasterized at
+	svgSize = 512
+)
 
-	img, _, err := image.Decode(ir)
+// decodeFrames decodes an image and, for animated gifs, each of its frames.
+func decodeFrames(data []byte) ([]image.Image, []time.Duration, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		cfg, err := gif.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, err
+		}
+		limit := maxAnimFrames
+		if px := cfg.Width * cfg.Height; px > 0 {
+			limit = max(1, min(limit, maxAnimPixels/px))
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(gifHead(data, limit)))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(g.Image) > 1 {
+			frames, delays := gifFrames(g)
+			return frames, delays, nil
+		}
+	case isSVG(data):
+		img, err := rasterizeSVG(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []image.Image{img}, []time.Duration{0}, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return "", err
+		return nil, nil, err
+	}
+	return []image.Image{img}, []time.Duration{0}, nil
+}
+
+// gifHead cuts a gif after its first n frames, so decoding stops there.
+// Malformed data is returned as it is for the decoder to reject.
+func gifHead(data []byte, n int) []byte {
+	// header and logical screen descriptor
+	i := 13
+	if len(data) < i {
+		return data
+	}
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&7 + 1)
+	}
+	// skipBlocks moves past data sub-blocks, ending at their terminator
+	skipBlocks := func() bool {
+		for i < len(data) {
+			size := int(data[i])
+			i += size + 1
+			if size == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	frames := 0
+	for i < len(data) {
+		switch data[i] {
+		case 0x21: // extension
+			i += 2
+			if !skipBlocks() {
+				return data
+			}
+		case 0x2c: // image descriptor
+			if i+10 > len(data) {
+				return data
+			}
+			packed := data[i+9]
+			i += 10
+			if packed&0x80 != 0 {
+				i += 3 << (packed&7 + 1)
+			}
+			// lzw minimum code size
+			i++
+			if !skipBlocks() {
+				return data
+			}
+			frames++
+			if frames == n && i < len(data) {
+				return append(data[:i:i], 0x3b)
+			}
+		default: // trailer or garbage
+			return data
+		}
+	}
+	return data
+}
+
+// gifFrames composes the frames of an animated gif, which may only draw
+// what changed since the last one.
+func gifFrames(g *gif.GIF) ([]image.Image, []time.Duration) {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		for _, p := range g.Image {
+			bounds = bounds.Union(p.Bounds())
+		}
 	}
-	// Check if the decoded image is of type NRGBA (non-alpha-premultiplied color)
-	// If it's not, convert it to NRGBA
-	// needed for bubbletea
-	if _, ok := img.(*image.NRGBA); !ok {
-		rgba := image.NewNRGBA(img.Bounds())
-		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
-		img = rgba
+	canvas := image.NewNRGBA(bounds)
+	var frames []image.Image
+	var delays []time.Duration
+	for i, p := range g.Image {
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var prev *image.NRGBA
+		if disposal == gif.DisposalPrevious {
+			prev = image.NewNRGBA(bounds)
+			copy(prev.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, p.Bounds(), p, p.Bounds().Min, draw.Over)
+
+		// browsers play delays this short at 10fps, as should we
+		delay := 100 * time.Millisecond
+		if i < len(g.Delay) && g.Delay[i] > 1 {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		frame := image.NewNRGBA(bounds)
+		copy(frame.Pix, canvas.Pix)
+		frames = append(frames, frame)
+		delays = append(delays, delay)
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, p.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = prev
+		}
 	}
+	return frames, delays
+}
 
-	return ToString(width, img), nil
+func isSVG(data []byte) bool {
+	head := data[:min(len(data), 1024)]
+	return bytes.Contains(head, []byte("<svg"))
+}
+
+// rasterizeSVG draws an svg with its longest side svgSize pixels, to be
+// resized like any other image.
+func rasterizeSVG(data []byte) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(bytes.NewReader(data), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	w, h := icon.ViewBox.W, icon.ViewBox.H
+	if w <= 0 || h <= 0 {
+		w, h = svgSize, svgSize
+	}
+	scale := svgSize / max(w, h)
+	pw, ph := max(1, int(w*scale)), max(1, int(h*scale))
+	icon.SetTarget(0, 0, float64(pw), float64(ph))
+	img := image.NewNRGBA(image.Rect(0, 0, pw, ph))
+	icon.Draw(rasterx.NewDasher(pw, ph, rasterx.NewScannerGV(pw, ph, img, img.Bounds())), 1)
+	return img, nil
 }
 
 // ImageModel represents the properties of a code bubble.
 type ImageModel struct {
 	Viewport    *viewport.Model
-	BorderColor lipgloss.AdaptiveColor
+	app         *App
 	Active      bool
 	Borderless  bool
 	URL         string
 	isEmbed     bool
 	ImageString string
+	anim        *animation
+	store       db.Store
+	fetches     chan struct{}
 }
 
 // New creates a new instance of code.
-func NewImage(active, borderless bool, borderColor lipgloss.AdaptiveColor) *ImageModel {
+func NewImage(app *App, active, borderless bool) *ImageModel {
 	viewPort := viewport.New(0, 0)
 	border := lipgloss.NormalBorder()
 
@@ -236,17 +489,19 @@ func NewImage(active, borderless bool, borderColor lipgloss.AdaptiveColor) *Imag
 		border = lipgloss.HiddenBorder()
 	}
 
-	viewPort.Style = NewStyle().
+	viewPort.Style = app.styles.New().
 		PaddingLeft(padding).
 		PaddingRight(padding).
 		Border(border).
-		BorderForeground(borderColor)
+		BorderForeground(app.theme.Special)
 
 	return &ImageModel{
-		Viewport:    &viewPort,
-		Active:      active,
-		Borderless:  borderless,
-		BorderColor: borderColor,
+		Viewport:   &viewPort,
+		app:        app,
+		Active:     active,
+		Borderless: borderless,
+		store:      app.store,
+		fetches:    app.imageFetches,
 	}
 }
 
@@ -258,6 +513,7 @@ func (m ImageModel) Init() tea.Cmd {
 func (m *ImageModel) Clear() {
 	m.URL = ""
 	m.ImageString = ""
+	m.anim = nil
 	m.Viewport.SetContent("")
 	m.Viewport.Width = 0
 	m.Viewport.Height = 0
@@ -270,7 +526,25 @@ func (m *ImageModel) Render() tea.Cmd {
 	if m.URL == "" {
 		return nil
 	}
-	return getImageCmd(m.Viewport.Width, m.URL, m.isEmbed)
+	if m.app.ctx.s != nil && isVideo(m.URL) {
+		// the server doesn't run ffmpeg on urls taken from casts
+		url := m.URL
+		return func() tea.Msg {
+			return convertImageToStringMsg{url: url, str: videoPlaceholder}
+		}
+	}
+	w, h := m.Viewport.Width, m.Viewport.Height
+	if m.app.graphics != graphicsHalfblocks {
+		// keep within the viewport's frame, which small images overflow
+		fx, fy := m.Viewport.Style.GetFrameSize()
+		if w > fx {
+			w -= fx
+		}
+		if h > fy {
+			h -= fy
+		}
+	}
+	return getImageCmd(m.app.ctx.r, m.app.graphics, m.store, m.fetches, w, h, m.URL, m.isEmbed)
 }
 
 func (m *ImageModel) SetURL(url string, embed bool) {
@@ -278,11 +552,6 @@ func (m *ImageModel) SetURL(url string, embed bool) {
 	m.isEmbed = embed
 }
 
-// SetBorderColor sets the current color of the border.
-func (m *ImageModel) SetBorderColor(color lipgloss.AdaptiveColor) {
-	m.BorderColor = color
-}
-
 func (m *ImageModel) SetSize(w, h int) {
 	m.Viewport.Width = w
 	m.Viewport.Height = h
@@ -293,11 +562,11 @@ func (m *ImageModel) SetSize(w, h int) {
 		border = lipgloss.HiddenBorder()
 	}
 
-	m.Viewport.Style = NewStyle().
+	m.Viewport.Style = m.app.styles.New().
 		PaddingLeft(padding).
 		PaddingRight(padding).
 		Border(border).
-		BorderForeground(m.BorderColor)
+		BorderForeground(m.app.theme.Special)
 }
 
 // SetIsActive sets if the bubble is currently active
@@ -337,23 +606,32 @@ func (m *ImageModel) Update(msg tea.Msg) (*ImageModel, tea.Cmd) {
 	switch msg := msg.(type) {
 	case convertImageToStringMsg:
 		if msg.url == m.URL && msg.str != "" {
-			m.ImageString = NewStyle().
+			style := m.app.styles.New().
 				Width(m.Viewport.Width).
-				Height(m.Viewport.Height).
-				Render(msg.str)
+				Height(m.Viewport.Height)
+			m.ImageString = style.Render(msg.str)
+			m.anim = nil
+			if len(msg.frames) > 1 {
+				frames := make([]string, len(msg.frames))
+				for i, f := range msg.frames {
+					frames[i] = style.Render(f)
+				}
+				m.anim = newAnimation(frames, msg.delays)
+				m.app.showAnimation(m.anim)
+			}
 			m.Viewport.SetContent(m.ImageString)
 			m.SetSize(m.Viewport.Width, m.Viewport.Height)
 		}
 	case downloadError:
 		if msg.url == m.URL {
-			m.ImageString = NewStyle().
+			m.ImageString = m.app.styles.New().
 				Width(m.Viewport.Width).
 				Height(m.Viewport.Height).
 				Render("Error: " + msg.err.Error())
 		}
 	case decodeError:
 		if msg.url == m.URL {
-			m.ImageString = NewStyle().
+			m.ImageString = m.app.styles.New().
 				Width(m.Viewport.Width).
 				Height(m.Viewport.Height).
 				Render("Error: " + msg.err.Error())
@@ -371,11 +649,21 @@ func (m ImageModel) View() string {
 		border = lipgloss.HiddenBorder()
 	}
 
-	m.Viewport.Style = NewStyle().
+	m.Viewport.Style = m.app.styles.New().
 		PaddingLeft(padding).
 		PaddingRight(padding).
 		Border(border).
-		BorderForeground(m.BorderColor)
+		BorderForeground(m.app.theme.Special)
 
+	if m.anim != nil {
+		m.app.showAnimation(m.anim)
+		m.Viewport.SetContent(m.anim.current())
+	}
 	return m.Viewport.View()
 }
+
+// Animated reports whether the image has frames to play, so views that
+// cache what they draw know to redraw it.
+func (m *ImageModel) Animated() bool {
+	return m.anim != nil
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+)
+
+func TestAnimatedImages(t *testing.T) {
+	var buf bytes.Buffer
+	g := &gif.GIF{}
+	for i := 0; i < 100; i++ {
+		p := image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black, color.White})
+		p.SetColorIndex(i%8, 0, 1)
+		g.Image = append(g.Image, p)
+		g.Delay = append(g.Delay, 5)
+	}
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	frames, delays, err := decodeFrames(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// decoding stops at the frame budget
+	if len(frames) != maxAnimFrames || delays[0] != 50*time.Millisecond {
+		t.Errorf("expected the first %d frames at 50ms, got %d at %s", maxAnimFrames, len(frames), delays[0])
+	}
+
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 20 10"><rect width="20" height="10" fill="red"/></svg>`
+	frames, _, err = decodeFrames([]byte(svg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := frames[0].Bounds(); b.Dx() != svgSize || b.Dy() != svgSize/2 {
+		t.Errorf("expected the svg rasterized at %dx%d, got %v", svgSize, svgSize/2, b)
+	}
+
+	app := NewApp(testCfg, testClient, &AppContext{signer: testSigner, pk: "local"}, false)
+	an := newAnimation([]string{"a", "b"}, []time.Duration{animInterval, animInterval})
+	now := time.Now()
+	for i := 1; i <= 2; i++ {
+		// drawn just before each tick
+		tick := now.Add(time.Duration(i) * animInterval)
+		app.animations[an] = tick.Add(-time.Millisecond)
+		app.tickAnimations(tick)
+	}
+	if an.current() != "b" {
+		t.Errorf("expected the animation to advance while drawn, got %q", an.current())
+	}
+	app.tickAnimations(now.Add(10 * animInterval))
+	if an.current() != "b" {
+		t.Error("expected the animation to pause when not drawn")
+	}
+
+	// over ssh videos get a placeholder without running ffmpeg
+	remote := NewApp(testCfg, testClient, &AppContext{s: fakeSession{}, signer: testSigner, pk: "remote"}, false)
+	img := NewImage(remote, true, true)
+	img.SetSize(20, 10)
+	img.SetURL("https://stream.warpcast.com/v1/video/abc/video.m3u8", true)
+	if msg, ok := img.Render()().(convertImageToStringMsg); !ok || msg.str != videoPlaceholder {
+		t.Errorf("expected a video placeholder over ssh, got %+v", msg)
+	}
+
+	for u, want := range map[string]bool{
+		"https://stream.warpcast.com/v1/video/abc/video.m3u8": true,
+		"https://example.com/clip.MP4?x=1":                    true,
+		"https://example.com/pfp.png":                         false,
+		"file:///etc/video.mp4":                               false,
+	} {
+		if isVideo(u) != want {
+			t.Errorf("expected isVideo(%q) to be %v", u, want)
+		}
+	}
+}
+
+// fakeSession stands in for an SSH session where only its presence matters.
+type fakeSession struct {
+	ssh.Session
+}
@@ -1,7 +1,11 @@
 package ui
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,6 +18,7 @@ type casetViewKeymap struct {
 	ViewParent  key.Binding
 	Comment     key.Binding
 	OpenCast    key.Binding
+	PlayVideo   key.Binding
 }
 
 func (k casetViewKeymap) ShortHelp() []key.Binding {
@@ -31,6 +36,7 @@ func (k casetViewKeymap) All() []key.Binding {
 		k.ViewParent,
 		k.Comment,
 		k.OpenCast,
+		k.PlayVideo,
 	}
 }
 
@@ -55,37 +61,12 @@ func (k casetViewKeymap) HandleMsg(c *CastView, msg tea.KeyMsg) tea.Cmd {
 		return noOp()
 	case key.Matches(msg, k.OpenCast):
 		return c.OpenCast()
+	case key.Matches(msg, k.PlayVideo):
+		return c.PlayVideo()
 	}
 	return nil
 }
 
-var CastViewKeyMap = casetViewKeymap{
-	LikeCast: key.NewBinding(
-		key.WithKeys("l"),
-		key.WithHelp("l", "like cast"),
-	),
-	ViewProfile: key.NewBinding(
-		key.WithKeys("p"),
-		key.WithHelp("p", "view profile"),
-	),
-	ViewChannel: key.NewBinding(
-		key.WithKeys("c"),
-		key.WithHelp("c", "view channel"),
-	),
-	ViewParent: key.NewBinding(
-		key.WithKeys("t"),
-		key.WithHelp("t", "view parent"),
-	),
-	Comment: key.NewBinding(
-		key.WithKeys("r"),
-		key.WithHelp("r", "reply"),
-	),
-	OpenCast: key.NewBinding(
-		key.WithKeys("o"),
-		key.WithHelp("o", "open in browser"),
-	),
-}
-
 type feedKeymap struct {
 	ViewCast    key.Binding
 	LikeCast    key.Binding
@@ -114,47 +95,24 @@ func (k feedKeymap) All() []key.Binding {
 func (k feedKeymap) HandleMsg(f *FeedView, msg tea.KeyMsg) tea.Cmd {
 	switch {
 	case key.Matches(msg, k.ViewCast):
-		log.Println("ViewCast")
+		slog.Debug("ViewCast")
 		return f.SelectCurrentItem()
 	case key.Matches(msg, k.LikeCast):
-		log.Println("LikeCast")
+		slog.Debug("LikeCast")
 		return f.LikeCurrentItem()
 	case key.Matches(msg, k.ViewProfile):
-		log.Println("ViewProfile")
+		slog.Debug("ViewProfile")
 		return f.ViewCurrentProfile()
 	case key.Matches(msg, k.ViewChannel):
-		log.Println("ViewChannel")
+		slog.Debug("ViewChannel")
 		return f.ViewCurrentChannel()
 	case key.Matches(msg, k.OpenCast):
-		log.Println("OpenCast")
+		slog.Debug("OpenCast")
 		return f.OpenCurrentItem()
 	}
 	return nil
 }
 
-var FeedKeyMap = feedKeymap{
-	ViewCast: key.NewBinding(
-		key.WithKeys("enter"),
-		key.WithHelp("enter", "view cast"),
-	),
-	LikeCast: key.NewBinding(
-		key.WithKeys("l"),
-		key.WithHelp("l", "like cast"),
-	),
-	ViewProfile: key.NewBinding(
-		key.WithKeys("p"),
-		key.WithHelp("p", "view profile"),
-	),
-	ViewChannel: key.NewBinding(
-		key.WithKeys("c"),
-		key.WithHelp("c", "view channel"),
-	),
-	OpenCast: key.NewBinding(
-		key.WithKeys("o"),
-		key.WithHelp("o", "open in browser "),
-	),
-}
-
 type navKeymap struct {
 	Feed key.Binding
 
@@ -165,6 +123,9 @@ type navKeymap struct {
 	ToggleSidebarVisibility key.Binding
 	Previous                key.Binding
 	ViewNotifications       key.Binding
+	SwitchAccount           key.Binding
+	Theme                   key.Binding
+	Quit                    key.Binding
 }
 
 func (k navKeymap) ShortHelp() []key.Binding {
@@ -181,47 +142,15 @@ func (k navKeymap) All() []key.Binding {
 		k.Feed, k.QuickSelect,
 		k.Publish,
 		k.ViewNotifications,
+		k.SwitchAccount,
+		k.Theme,
 		k.Previous,
 		k.Help,
 		k.ToggleSidebarFocus, k.ToggleSidebarVisibility,
+		k.Quit,
 	}
 }
 
-var NavKeyMap = navKeymap{
-	Feed: key.NewBinding(
-		key.WithKeys("F", "1"),
-		key.WithHelp("F/1", "feed"),
-	),
-	Publish: key.NewBinding(
-		key.WithKeys("P"),
-		key.WithHelp("P", "publish cast"),
-	),
-	QuickSelect: key.NewBinding(
-		key.WithKeys("ctrl+k"),
-		key.WithHelp("ctrl+k", "quick select"),
-	),
-	Help: key.NewBinding(
-		key.WithKeys("?"),
-		key.WithHelp("?", "help"),
-	),
-	ToggleSidebarFocus: key.NewBinding(
-		key.WithKeys("tab"),
-		key.WithHelp("tab", "toggle sidebar focus"),
-	),
-	ToggleSidebarVisibility: key.NewBinding(
-		key.WithKeys("shift+tab"),
-		key.WithHelp("shift+tab", "toggle sidebar focus"),
-	),
-	Previous: key.NewBinding(
-		key.WithKeys("esc"),
-		key.WithHelp("esc", "focus previous"),
-	),
-	ViewNotifications: key.NewBinding(
-		key.WithKeys("N"),
-		key.WithHelp("N", "view notifications"),
-	),
-}
-
 func (k navKeymap) HandleMsg(a *App, msg tea.KeyMsg) tea.Cmd {
 	switch {
 	case key.Matches(msg, k.Feed):
@@ -244,12 +173,26 @@ func (k navKeymap) HandleMsg(a *App, msg tea.KeyMsg) tea.Cmd {
 		a.FocusHelp()
 
 	case key.Matches(msg, k.ViewNotifications):
-		log.Println("ViewNotifications")
+		slog.Debug("ViewNotifications")
 		return a.FocusNotifications()
 
 	case key.Matches(msg, k.Previous):
 		return a.FocusPrev()
 
+	case key.Matches(msg, k.SwitchAccount):
+		return a.SwitchAccount()
+
+	case key.Matches(msg, k.Theme):
+		a.NextTheme()
+		return noOp()
+
+	case key.Matches(msg, k.Quit):
+		// the quick select filter takes the key instead
+		if a.quickSelect.Active() {
+			return nil
+		}
+		return tea.Quit
+
 	case key.Matches(msg, k.ToggleSidebarVisibility):
 		if a.showSidebar {
 			a.showSidebar = false
@@ -274,27 +217,244 @@ func (k navKeymap) HandleMsg(a *App, msg tea.KeyMsg) tea.Cmd {
 	return nil
 }
 
-type kmap struct {
-	nav  navKeymap
-	feed feedKeymap
-	cast casetViewKeymap
+type publishKeymap struct {
+	Cast          key.Binding
+	Back          key.Binding
+	ChooseChannel key.Binding
+}
+
+func (k publishKeymap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Cast, k.Back, k.ChooseChannel}
 }
 
-var GlobalKeyMap = kmap{
-	nav:  NavKeyMap,
-	feed: FeedKeyMap,
-	cast: CastViewKeyMap,
+func (k publishKeymap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Cast},
+		{k.Back},
+	}
+}
+
+func (k publishKeymap) All() []key.Binding {
+	return []key.Binding{k.Cast, k.Back, k.ChooseChannel}
+}
+
+// Keys holds every binding that can be remapped from the keys section of
+// the config.
+type Keys struct {
+	Nav     navKeymap
+	Feed    feedKeymap
+	Cast    casetViewKeymap
+	Publish publishKeymap
+}
+
+// bind makes a binding whose help lists its keys.
+func bind(desc string, keys ...string) key.Binding {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k
+		if k == " " {
+			names[i] = "space"
+		}
+	}
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(strings.Join(names, "/"), desc))
+}
+
+func DefaultKeys() *Keys {
+	return &Keys{
+		Nav: navKeymap{
+			Feed:                    bind("feed", "F", "1"),
+			Publish:                 bind("publish cast", "P"),
+			QuickSelect:             bind("quick select", "ctrl+k"),
+			Help:                    bind("help", "?"),
+			ToggleSidebarFocus:      bind("toggle sidebar focus", "tab"),
+			ToggleSidebarVisibility: bind("toggle sidebar", "shift+tab"),
+			Previous:                bind("focus previous", "esc"),
+			ViewNotifications:       bind("view notifications", "N"),
+			SwitchAccount:           bind("switch account", "A"),
+			Theme:                   bind("switch theme", "T"),
+			Quit:                    bind("quit", "q"),
+		},
+		Feed: feedKeymap{
+			ViewCast:    bind("view cast", "enter"),
+			LikeCast:    bind("like cast", "l"),
+			ViewProfile: bind("view profile", "p"),
+			ViewChannel: bind("view channel", "c"),
+			OpenCast:    bind("open in browser", "o"),
+		},
+		Cast: casetViewKeymap{
+			LikeCast:    bind("like cast", "l"),
+			ViewProfile: bind("view profile", "p"),
+			ViewChannel: bind("view channel", "c"),
+			ViewParent:  bind("view parent", "t"),
+			Comment:     bind("reply", "r"),
+			OpenCast:    bind("open in browser", "o"),
+			PlayVideo:   bind("play video", "v"),
+		},
+		Publish: publishKeymap{
+			Cast:          bind("publish cast", "ctrl+d"),
+			Back:          bind("back to feed", "esc"),
+			ChooseChannel: bind("choose channel", "ctrl+w"),
+		},
+	}
+}
+
+type keyAction struct {
+	name    string
+	binding *key.Binding
+}
+
+// actions names every binding, as used in the keys section of the config.
+func (k *Keys) actions() []keyAction {
+	return []keyAction{
+		{"nav.feed", &k.Nav.Feed},
+		{"nav.publish", &k.Nav.Publish},
+		{"nav.quick_select", &k.Nav.QuickSelect},
+		{"nav.help", &k.Nav.Help},
+		{"nav.toggle_sidebar_focus", &k.Nav.ToggleSidebarFocus},
+		{"nav.toggle_sidebar", &k.Nav.ToggleSidebarVisibility},
+		{"nav.previous", &k.Nav.Previous},
+		{"nav.notifications", &k.Nav.ViewNotifications},
+		{"nav.switch_account", &k.Nav.SwitchAccount},
+		{"nav.theme", &k.Nav.Theme},
+		{"nav.quit", &k.Nav.Quit},
+		{"feed.view_cast", &k.Feed.ViewCast},
+		{"feed.like", &k.Feed.LikeCast},
+		{"feed.view_profile", &k.Feed.ViewProfile},
+		{"feed.view_channel", &k.Feed.ViewChannel},
+		{"feed.open", &k.Feed.OpenCast},
+		{"cast.like", &k.Cast.LikeCast},
+		{"cast.view_profile", &k.Cast.ViewProfile},
+		{"cast.view_channel", &k.Cast.ViewChannel},
+		{"cast.view_parent", &k.Cast.ViewParent},
+		{"cast.reply", &k.Cast.Comment},
+		{"cast.open", &k.Cast.OpenCast},
+		{"cast.play_video", &k.Cast.PlayVideo},
+		{"publish.cast", &k.Publish.Cast},
+		{"publish.back", &k.Publish.Back},
+		{"publish.choose_channel", &k.Publish.ChooseChannel},
+	}
+}
+
+// KeyActions lists the names of every action that can be remapped.
+func KeyActions() []string {
+	var names []string
+	for _, a := range DefaultKeys().actions() {
+		names = append(names, a.name)
+	}
+	return names
+}
+
+// NewKeys applies remap, from action name to keys, over the defaults. An
+// action remapped to no keys is disabled. Unknown actions and keys bound to
+// two actions that are active at the same time are errors.
+func NewKeys(remap map[string][]string) (*Keys, error) {
+	k := DefaultKeys()
+	actions := map[string]*key.Binding{}
+	for _, a := range k.actions() {
+		actions[a.name] = a.binding
+	}
+	var errs []error
+	for _, name := range sortedKeys(remap) {
+		b, ok := actions[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown action %q", name))
+			continue
+		}
+		keys := make([]string, len(remap[name]))
+		for i, kk := range remap[name] {
+			// bubbletea reports the space bar as " "
+			if kk == "space" {
+				kk = " "
+			}
+			keys[i] = kk
+		}
+		if len(keys) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+		*b = bind(b.Help().Desc, keys...)
+	}
+	errs = append(errs, k.conflicts()...)
+	return k, errors.Join(errs...)
+}
+
+// reservedKeys can't be remapped, ctrl+c always quits.
+var reservedKeys = map[string]bool{"ctrl+c": true}
+
+// conflicts finds keys bound to more than one action within the groups
+// that handle keys together. Nav bindings apply over the feed and cast
+// views, while publish only sees keys of its own.
+func (k *Keys) conflicts() []error {
+	var nav, feed, cast, publish []keyAction
+	for _, a := range k.actions() {
+		switch {
+		case strings.HasPrefix(a.name, "nav."):
+			nav = append(nav, a)
+		case strings.HasPrefix(a.name, "feed."):
+			feed = append(feed, a)
+		case strings.HasPrefix(a.name, "cast."):
+			cast = append(cast, a)
+		default:
+			publish = append(publish, a)
+		}
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	report := func(err error) {
+		if !seen[err.Error()] {
+			seen[err.Error()] = true
+			errs = append(errs, err)
+		}
+	}
+	groups := [][]keyAction{
+		append(append([]keyAction{}, nav...), feed...),
+		append(append([]keyAction{}, nav...), cast...),
+		publish,
+	}
+	for _, group := range groups {
+		bound := map[string]string{}
+		for _, a := range group {
+			if !a.binding.Enabled() {
+				continue
+			}
+			publishing := strings.HasPrefix(a.name, "publish.")
+			for _, kk := range a.binding.Keys() {
+				switch other, ok := bound[kk]; {
+				case publishing && len([]rune(kk)) == 1:
+					report(fmt.Errorf("%s: %s would be typed into the cast instead", a.name, kk))
+				case reservedKeys[kk]:
+					report(fmt.Errorf("%s: %s is reserved for quitting", a.name, kk))
+				case ok && other != a.name:
+					report(fmt.Errorf("%s is bound to both %s and %s", kk, other, a.name))
+				default:
+					bound[kk] = a.name
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-func (k kmap) ShortHelp() []key.Binding {
-	return append(k.nav.ShortHelp(), k.feed.ShortHelp()...)
+func (k *Keys) ShortHelp() []key.Binding {
+	return append(k.Nav.ShortHelp(), k.Feed.ShortHelp()...)
 }
 
-func (k kmap) FullHelp() [][]key.Binding {
+func (k *Keys) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		k.nav.All(),
-		k.feed.All(),
-		k.cast.All(),
+		k.Nav.All(),
+		k.Feed.All(),
+		k.Cast.All(),
+		k.Publish.All(),
 	}
 }
 
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeys(t *testing.T) {
+	k, err := NewKeys(map[string][]string{
+		"nav.quick_select": {"ctrl+p"},
+		"publish.cast":     {"ctrl+s", "alt+enter"},
+		"cast.open":        {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := tea.KeyMsg{Type: tea.KeyCtrlP}
+	if !key.Matches(msg, k.Nav.QuickSelect) {
+		t.Error("expected quick select to be remapped to ctrl+p")
+	}
+	if k.Cast.OpenCast.Enabled() {
+		t.Error("expected an action remapped to no keys to be disabled")
+	}
+
+	help := NewHelpView(nil, k)
+	help.SetSize(200, 40)
+	help.SetFull(true)
+	view := strings.Join(strings.Fields(help.View()), " ")
+	for _, want := range []string{"ctrl+p quick select", "ctrl+s/alt+enter publish cast"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected help to show %q, got\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "ctrl+k") {
+		t.Error("expected help not to show the replaced binding")
+	}
+
+	_, err = NewKeys(map[string][]string{"nav.help": {"l"}, "publish.back": {"y"}})
+	for _, want := range []string{"l is bound to both nav.help and feed.like", "publish.back: y would be typed"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected conflict %q, got %v", want, err)
+		}
+	}
+}
+
+func TestQuitKey(t *testing.T) {
+	quits := func(cmd tea.Cmd) bool {
+		if cmd == nil {
+			return false
+		}
+		_, ok := cmd().(tea.QuitMsg)
+		return ok
+	}
+	q := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}
+
+	cfg := *testCfg
+	cfg.Keys = map[string][]string{"nav.quit": {"ctrl+q"}}
+	a := NewApp(&cfg, testClient, &AppContext{signer: testSigner, pk: "local"}, false)
+	a.splash.SetActive(false)
+	if _, cmd := a.Update(q); quits(cmd) {
+		t.Error("expected q not to quit once quit is remapped")
+	}
+	if _, cmd := a.Update(tea.KeyMsg{Type: tea.KeyCtrlQ}); !quits(cmd) {
+		t.Error("expected the remapped key to quit")
+	}
+
+	a = NewApp(testCfg, testClient, &AppContext{signer: testSigner, pk: "local"}, false)
+	a.splash.SetActive(false)
+	a.FocusQuickSelect()
+	if _, cmd := a.Update(q); quits(cmd) {
+		t.Error("expected q to go to the quick switcher")
+	}
+	a.quickSelect.SetActive(false)
+	if _, cmd := a.Update(q); !quits(cmd) {
+		t.Error("expected q to quit by default")
+	}
+}
@@ -17,17 +17,30 @@ func tickCmd() tea.Cmd {
 
 type Loading struct {
 	prog   *progress.Model
+	styles *styles
 	active bool
 	pct    float64
 }
 
-func NewLoading() *Loading {
-	p := progress.New()
+func NewLoading(s *styles) *Loading {
+	m := &Loading{active: true}
+	m.SetStyles(s)
+	return m
+}
+
+// SetStyles redraws the bar in the theme of s, owners call it before
+// drawing so a theme change is picked up.
+func (m *Loading) SetStyles(s *styles) {
+	if s == m.styles {
+		return
+	}
+	m.styles = s
+	p := progress.New(progress.WithSolidFill(s.colorString(s.theme.Accent)), progress.WithColorProfile(s.r.ColorProfile()))
 	p.ShowPercentage = false
-	return &Loading{
-		active: true,
-		prog:   &p,
+	if m.prog != nil {
+		p.Width = m.prog.Width
 	}
+	m.prog = &p
 }
 
 func (m *Loading) IsActive() bool {
@@ -2,7 +2,7 @@ package ui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -21,7 +21,7 @@ func getNotificationsCmd(client *api.Client, signer *api.Signer) tea.Cmd {
 		}
 		resp, err := client.GetNotifications(signer.FID)
 		if err != nil {
-			log.Println("error getting notifications: ", err)
+			slog.Error("error getting notifications", "error", err)
 			return nil
 		}
 		return &notificationsMsg{notifications: resp.Notifications}
@@ -30,6 +30,7 @@ func getNotificationsCmd(client *api.Client, signer *api.Signer) tea.Cmd {
 
 type notifItem struct {
 	*api.Notification
+	app *App
 }
 
 func (n *notifItem) FilterValue() string {
@@ -82,7 +83,7 @@ func (n *notifItem) Title() string {
 		return fmt.Sprintf("%s  %s replied to your post", EmojiComment, n.Cast.Author.DisplayName)
 	case api.NotificationsTypeMention:
 		return fmt.Sprintf("%s  %s mentioned you in a post",
-			NewStyle().Bold(true).Foreground(activeColor).Render("@"), n.Cast.Author.DisplayName,
+			n.app.styles.New().Bold(true).Foreground(n.app.theme.Accent).Render("@"), n.Cast.Author.DisplayName,
 		)
 
 	default:
@@ -115,19 +116,21 @@ func (i *notifItem) Description() string {
 }
 
 type NotificationsView struct {
-	app    *App
-	list   *list.Model
-	w, h   int
-	active bool
-	items  []list.Item
+	app      *App
+	list     *list.Model
+	styles   *styles
+	delegate list.DefaultDelegate
+	w, h     int
+	active   bool
+	items    []list.Item
 }
 
 func NewNotificationsView(app *App) *NotificationsView {
-	d := list.NewDefaultDelegate()
+	d := app.styles.newDelegate()
 	d.SetHeight(2)
 	d.ShowDescription = true
 
-	l := list.New([]list.Item{}, d, 100, 100)
+	l := app.styles.newList(d, 100, 100)
 	l.KeyMap.CursorUp.SetKeys("k", "up")
 	l.KeyMap.CursorDown.SetKeys("j", "down")
 	l.KeyMap.Quit.SetKeys("ctrl+c")
@@ -139,7 +142,7 @@ func NewNotificationsView(app *App) *NotificationsView {
 	l.SetShowStatusBar(true)
 	l.SetShowPagination(true)
 
-	return &NotificationsView{app: app, list: &l}
+	return &NotificationsView{app: app, list: &l, styles: app.styles, delegate: d}
 }
 
 func (m *NotificationsView) SetSize(w, h int) {
@@ -167,7 +170,7 @@ func (m *NotificationsView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case *notificationsMsg:
 		items := []list.Item{}
 		for _, n := range msg.notifications {
-			items = append(items, &notifItem{n})
+			items = append(items, &notifItem{n, m.app})
 		}
 		m.items = items
 		m.list.SetItems(items)
@@ -175,8 +178,6 @@ func (m *NotificationsView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "q":
-			return m, tea.Quit
 		case "enter":
 			item, ok := m.list.SelectedItem().(*notifItem)
 			if !ok {
@@ -200,5 +201,9 @@ func (m *NotificationsView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *NotificationsView) View() string {
-	return NewStyle().Width(m.w).Height(m.h).Render(m.list.View())
+	if m.styles != m.app.styles {
+		m.styles = m.app.styles
+		m.styles.restyleList(m.list, &m.delegate)
+	}
+	return m.app.styles.New().Width(m.w).Height(m.h).Render(m.list.View())
 }
@@ -2,7 +2,7 @@ package ui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -10,23 +10,23 @@ import (
 	"github.com/treethought/tofui/api"
 )
 
-func UserBio(user *api.User) string {
+func UserBio(s *styles, user *api.User) string {
 	if user == nil {
-		l := NewLoading()
+		l := NewLoading(s)
 		l.SetActive(true)
 		return l.View()
 	}
 	stats := lipgloss.JoinHorizontal(lipgloss.Top,
-		NewStyle().Bold(true).Render(fmt.Sprintf("%d", user.FollowingCount)),
-		NewStyle().MarginRight(10).Render(" following"),
-		NewStyle().Bold(true).Render(fmt.Sprintf("%d", user.FollowerCount)),
-		NewStyle().Render(" followers"),
+		s.New().Bold(true).Render(fmt.Sprintf("%d", user.FollowingCount)),
+		s.New().MarginRight(10).Render(" following"),
+		s.New().Bold(true).Render(fmt.Sprintf("%d", user.FollowerCount)),
+		s.New().Render(" followers"),
 	)
 
-	style := NewStyle().BorderStyle(lipgloss.RoundedBorder()).BorderBottom(true).Padding(2)
+	style := s.New().BorderStyle(lipgloss.RoundedBorder()).BorderBottom(true).Padding(2)
 
 	return style.Render(lipgloss.JoinVertical(lipgloss.Top,
-		NewStyle().MarginTop(0).MarginBottom(0).Padding(0).Render(user.Profile.Bio.Text),
+		s.New().MarginTop(0).MarginBottom(0).Padding(0).Render(user.Profile.Bio.Text),
 		stats,
 	))
 
@@ -58,14 +58,14 @@ func NewProfile(app *App) *Profile {
 	f := NewFeedView(app, feedTypeProfile)
 	return &Profile{
 		app:  app,
-		pfp:  NewImage(false, true, special),
+		pfp:  NewImage(app, false, true),
 		feed: f,
 	}
 }
 
 func getUserCmd(client *api.Client, fid, viewer uint64) tea.Cmd {
 	return func() tea.Msg {
-		log.Println("get user by fid cmd", fid)
+		slog.Debug("getting user", "user_fid", fid)
 		user, err := client.GetUserByFID(fid, viewer)
 		return ProfileMsg{fid, user, err}
 	}
@@ -79,7 +79,7 @@ func getUserFeedCmd(client *api.Client, fid, viewer uint64) tea.Cmd {
 		}
 		feed, err := client.GetFeed(req)
 		if err != nil {
-			log.Println("feedview error getting feed", err)
+			slog.Error("feedview error getting feed", "error", err)
 			return err
 		}
 		return &profileFeedMsg{fid, feed.Casts}
@@ -107,8 +107,8 @@ func (m *Profile) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		x, y := msg.Width, msg.Height
 		m.pfp.SetSize(4, 4)
 
-		hy := lipgloss.Height(UsernameHeader(m.user, m.pfp))
-		by := lipgloss.Height(UserBio(m.user))
+		hy := lipgloss.Height(UsernameHeader(m.app.styles, m.user, m.pfp))
+		by := lipgloss.Height(UserBio(m.app.styles, m.user))
 
 		fy := y - hy - by
 
@@ -137,8 +137,8 @@ func (m *Profile) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 func (m *Profile) View() string {
 	return lipgloss.JoinVertical(lipgloss.Center,
-		UsernameHeader(m.user, m.pfp),
-		UserBio(m.user),
+		UsernameHeader(m.app.styles, m.user, m.pfp),
+		UserBio(m.app.styles, m.user),
 		m.feed.View(),
 	)
 }
@@ -2,7 +2,7 @@ package ui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -29,6 +29,9 @@ type ctxInfoMsg struct {
 func postCastCmd(client *api.Client, signer *api.Signer, text, parent, channel string, parentAuthor uint64) tea.Cmd {
 	return func() tea.Msg {
 		resp, err := client.PostCast(signer, text, parent, channel, parentAuthor)
+		if api.IsSignerRevoked(err) {
+			return &signerRevokedMsg{signer: signer}
+		}
 		if err != nil {
 			return &postResponseMsg{err: err}
 		}
@@ -36,38 +39,6 @@ func postCastCmd(client *api.Client, signer *api.Signer, text, parent, channel s
 	}
 }
 
-type keyMap struct {
-	Cast          key.Binding
-	Back          key.Binding
-	ChooseChannel key.Binding
-}
-
-func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Cast, k.Back, k.ChooseChannel}
-}
-
-func (k keyMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{
-		{k.Cast},
-		{k.Back},
-	}
-}
-
-var keys = keyMap{
-	Cast: key.NewBinding(
-		key.WithKeys("ctrl+d"),
-		key.WithHelp("ctrl+d", "publish cast"),
-	),
-	Back: key.NewBinding(
-		key.WithKeys("esc"),
-		key.WithHelp("esc", "back to feed"),
-	),
-	ChooseChannel: key.NewBinding(
-		key.WithKeys("ctrl+w"),
-		key.WithHelp("ctrl+w", "choose channel"),
-	),
-}
-
 type castContext struct {
 	channel      string
 	parent       string
@@ -77,7 +48,7 @@ type castContext struct {
 
 type PublishInput struct {
 	app         *App
-	keys        keyMap
+	keys        publishKeymap
 	help        help.Model
 	ta          *textarea.Model
 	vp          *viewport.Model
@@ -90,6 +61,8 @@ type PublishInput struct {
 
 func NewPublishInput(app *App) *PublishInput {
 	ta := textarea.New()
+	app.styles.adopt(&ta.FocusedStyle)
+	app.styles.adopt(&ta.BlurredStyle)
 	if app.ctx.signer == nil {
 		ta.Placeholder = "please sign in to post"
 	} else {
@@ -104,7 +77,7 @@ func NewPublishInput(app *App) *PublishInput {
 
 	qs := NewQuickSelect(app)
 
-	return &PublishInput{ta: &ta, vp: &vp, keys: keys, help: help.New(), app: app, qs: qs}
+	return &PublishInput{ta: &ta, vp: &vp, keys: app.keys.Publish, help: app.styles.newHelp(), app: app, qs: qs}
 }
 
 func (m *PublishInput) Init() tea.Cmd {
@@ -148,17 +121,17 @@ func (m *PublishInput) SetContext(parent, channelParentUrl string, parentAuthor
 		if parentAuthor > 0 {
 			parentUser, err = m.app.client.GetUserByFID(parentAuthor, viewer)
 			if err != nil {
-				log.Println("error getting parent author: ", err)
+				slog.Error("error getting parent author", "error", err)
 				return nil
 			}
 		}
 		if channelParentUrl != "" {
 			channel, err = m.app.client.GetChannelByParentUrl(channelParentUrl)
 			if err != nil {
-				log.Println("error getting channel by parent url, trying channel id: ", err)
+				slog.Error("error getting channel by parent url, trying channel id", "error", err)
 				channel, err = m.app.client.GetChannelById(channelParentUrl)
 				if err != nil {
-					log.Println("error getting channel by id: ", err)
+					slog.Error("error getting channel by id", "error", err)
 					return nil
 				}
 			}
@@ -192,15 +165,15 @@ func (m *PublishInput) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case *postResponseMsg:
 		if msg.err != nil {
-			log.Println("error posting cast: ", msg.err)
-			m.vp.SetContent(NewStyle().Foreground(lipgloss.Color("#ff0000")).Render("error posting cast!"))
+			m.app.logger().Error("failed to post cast", "error", msg.err)
+			m.vp.SetContent(m.app.styles.New().Foreground(m.app.theme.Error).Render("error posting cast!"))
 			return m, nil
 		}
 		if msg.resp == nil || !msg.resp.Success {
-			m.vp.SetContent(NewStyle().Foreground(lipgloss.Color("#ff0000")).Render("error posting cast!"))
+			m.vp.SetContent(m.app.styles.New().Foreground(m.app.theme.Error).Render("error posting cast!"))
 			return m, nil
 		}
-		log.Println("cast posted: ", msg.resp.Cast.Hash)
+		m.app.logger().Info("cast posted", "cast", msg.resp.Cast.Hash)
 		m.Clear()
 		m.SetActive(false)
 		return m, m.app.GoToCast(msg.resp.Cast.Hash)
@@ -254,7 +227,7 @@ func (m *PublishInput) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *PublishInput) viewConfirm() string {
-	header := NewStyle().BorderBottom(true).BorderStyle(lipgloss.NormalBorder()).Render(confirmPrefix)
+	header := m.app.styles.New().BorderBottom(true).BorderStyle(lipgloss.NormalBorder()).Render(confirmPrefix)
 	return lipgloss.JoinVertical(lipgloss.Top,
 		header, m.ta.View())
 }
@@ -280,18 +253,18 @@ func (m *PublishInput) View() string {
 		titleText = fmt.Sprintf("publish cast to channel: /%s", m.castCtx.channel)
 	}
 
-	titleStyle := NewStyle().Foreground(lipgloss.Color("#874BFD")).BorderBottom(true).BorderStyle(lipgloss.NormalBorder())
+	titleStyle := m.app.styles.New().Foreground(m.app.theme.Accent).BorderBottom(true).BorderStyle(lipgloss.NormalBorder())
 	title := titleStyle.Render(titleText)
 
 	dialog := lipgloss.Place(m.w/2, m.h/2,
 		lipgloss.Center, lipgloss.Center,
 		lipgloss.JoinVertical(lipgloss.Top,
 			title,
-			dialogBoxStyle.Width(m.w).Height(m.h).Render(content),
+			m.app.styles.dialogBox.Width(m.w).Height(m.h).Render(content),
 		),
 		// lipgloss.WithWhitespaceChars("猫咪"),
 		lipgloss.WithWhitespaceChars("~~"),
-		lipgloss.WithWhitespaceForeground(subtle),
+		lipgloss.WithWhitespaceForeground(m.app.theme.Subtle),
 	)
 	return dialog
 }
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrQuietZone is the light border around the code, in modules. The spec
+// asks for 4 but 2 scans fine and keeps the code small enough for a
+// terminal.
+const qrQuietZone = 2
+
+// renderQR draws content as a QR code using half blocks, so each line of
+// text holds two rows of modules. style should be dark on light since not
+// every scanner reads inverted codes.
+func renderQR(style lipgloss.Style, content string) (string, error) {
+	q, err := qrcode.New(content, qrcode.Low)
+	if err != nil {
+		return "", err
+	}
+	q.DisableBorder = true
+	bm := q.Bitmap()
+	size := len(bm) + 2*qrQuietZone
+	dark := func(x, y int) bool {
+		x, y = x-qrQuietZone, y-qrQuietZone
+		if x < 0 || y < 0 || x >= len(bm) || y >= len(bm) {
+			return false
+		}
+		return bm[y][x]
+	}
+
+	lines := make([]string, 0, (size+1)/2)
+	for y := 0; y < size; y += 2 {
+		var b strings.Builder
+		for x := 0; x < size; x++ {
+			top, bottom := dark(x, y), dark(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top:
+				b.WriteRune('▀')
+			case bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		lines = append(lines, style.Render(b.String()))
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -1,7 +1,7 @@
 package ui
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -14,6 +14,8 @@ type QuickSelect struct {
 	app         *App
 	active      bool
 	channelList *list.Model
+	styles      *styles
+	delegate    list.DefaultDelegate
 	w, h        int
 	onSelect    func(i *selectItem) tea.Cmd
 }
@@ -40,11 +42,11 @@ func (i *selectItem) Description() string {
 }
 
 func NewQuickSelect(app *App) *QuickSelect {
-	d := list.NewDefaultDelegate()
+	d := app.styles.newDelegate()
 	d.SetHeight(1)
 	d.ShowDescription = false
 
-	l := list.New([]list.Item{}, d, 100, 100)
+	l := app.styles.newList(d, 100, 100)
 	l.KeyMap.CursorUp.SetKeys("k", "up")
 	l.KeyMap.CursorDown.SetKeys("j", "down")
 	l.KeyMap.Quit.SetKeys("ctrl+c")
@@ -56,7 +58,7 @@ func NewQuickSelect(app *App) *QuickSelect {
 	l.SetShowStatusBar(true)
 	l.SetShowPagination(true)
 
-	return &QuickSelect{app: app, channelList: &l}
+	return &QuickSelect{app: app, channelList: &l, styles: app.styles, delegate: d}
 }
 
 type channelListMsg struct {
@@ -67,7 +69,7 @@ type channelListMsg struct {
 func getUserChannels(client *api.Client, fid uint64, activeOnly bool) tea.Msg {
 	channels, err := client.GetUserChannels(fid, activeOnly, api.WithLimit(100))
 	if err != nil {
-		log.Println("error getting user channels: ", err)
+		slog.Error("error getting user channels", "error", err)
 		return nil
 	}
 	return &channelListMsg{channels, activeOnly}
@@ -81,12 +83,12 @@ func getChannelsCmd(client *api.Client, activeOnly bool, fid uint64) tea.Cmd {
 		msg := &channelListMsg{}
 		ids, err := client.GetCachedChannelIds()
 		if err != nil {
-			log.Println("error getting channel names: ", err)
+			slog.Error("error getting channel names", "error", err)
 		}
 		for _, id := range ids {
 			channel, err := client.GetChannelById(id)
 			if err != nil {
-				log.Println("error getting channel: ", err)
+				slog.Error("error getting channel", "error", err)
 				continue
 			}
 			msg.channels = append(msg.channels, channel)
@@ -141,11 +143,11 @@ func (m *QuickSelect) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			currentItem, ok := m.channelList.SelectedItem().(*selectItem)
 			if !ok {
-				log.Println("no item selected")
+				slog.Debug("no item selected")
 				return m, nil
 			}
 			if currentItem.name == "profile" {
-				log.Println("profile selected")
+				slog.Debug("profile selected")
 				if m.app.ctx.signer == nil {
 					return m, nil
 				}
@@ -155,11 +157,11 @@ func (m *QuickSelect) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				)
 			}
 			if currentItem.name == "feed" {
-				log.Println("feed selected")
+				slog.Debug("feed selected")
 				return m, tea.Sequence(m.app.FocusFeed(), getDefaultFeedCmd(m.app.client, m.app.ctx.signer))
 			}
 			if currentItem.itype == "channel" {
-				log.Println("channel selected")
+				slog.Debug("channel selected")
 				return m, tea.Sequence(
 					m.app.FocusChannel(),
 					getFeedCmd(m.app.client, &api.FeedRequest{
@@ -175,14 +177,16 @@ func (m *QuickSelect) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-var dialogBoxStyle = NewStyle()
-
 func (m *QuickSelect) View() string {
+	if m.styles != m.app.styles {
+		m.styles = m.app.styles
+		m.styles.restyleList(m.channelList, &m.delegate)
+	}
 	dialog := lipgloss.Place(m.h, m.h,
 		lipgloss.Center, lipgloss.Center,
-		dialogBoxStyle.Render(m.channelList.View()),
+		m.app.styles.dialogBox.Render(m.channelList.View()),
 		lipgloss.WithWhitespaceChars("~~"),
-		lipgloss.WithWhitespaceForeground(subtle),
+		lipgloss.WithWhitespaceForeground(m.app.theme.Subtle),
 	)
 	return dialog
 }
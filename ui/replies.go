@@ -1,7 +1,7 @@
 package ui
 
 import (
-	"log"
+	"log/slog"
 
 	tea "github.com/charmbracelet/bubbletea"
 
@@ -56,13 +56,13 @@ func (m *RepliesView) SetOpHash(hash string) tea.Cmd {
 	m.Clear()
 	m.opHash = hash
 	if m.app == nil {
-		log.Println("app is nil")
+		slog.Debug("app is nil")
 	}
 	if m.app.ctx == nil {
-		log.Println("app context is nil")
+		slog.Debug("app context is nil")
 	}
 	if m.app.ctx.signer == nil {
-		log.Println("signer is nil")
+		slog.Debug("signer is nil")
 	}
 
 	return getConvoCmd(m.app.client, m.app.ctx.signer, hash)
@@ -76,7 +76,7 @@ func (m *RepliesView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case *repliesMsg:
 		if msg.err != nil {
-			log.Println("error getting convo: ", msg.err)
+			m.app.logger().Error("failed to get conversation", "error", msg.err)
 			return m, nil
 		}
 		m.Clear()
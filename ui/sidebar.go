@@ -2,7 +2,7 @@ package ui
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -16,8 +16,13 @@ type Sidebar struct {
 	active  bool
 	nav     *list.Model
 	account *api.User
-	pfp     *ImageModel
-	w, h    int
+	// styles the nav was drawn with, and its delegate
+	styles   *styles
+	delegate list.DefaultDelegate
+	// other accounts signed in with this public key
+	accounts []*api.Signer
+	pfp      *ImageModel
+	w, h     int
 }
 
 type currentAccountMsg struct {
@@ -31,7 +36,7 @@ func getCurrentAccount(client *api.Client, signer *api.Signer) tea.Cmd {
 		}
 		user, err := client.GetUserByFID(signer.FID, signer.FID)
 		if err != nil {
-			log.Println("error getting current account: ", err)
+			slog.Error("error getting current account", "error", err)
 			return nil
 		}
 		return &currentAccountMsg{account: user}
@@ -59,14 +64,12 @@ func (i *sidebarItem) Description() string {
 	return ""
 }
 
-var navStyle = NewStyle().Margin(2, 2, 0, 0).BorderRight(true).BorderStyle(lipgloss.RoundedBorder())
-
 func NewSidebar(app *App) *Sidebar {
-	d := list.NewDefaultDelegate()
+	d := app.styles.newDelegate()
 	d.SetHeight(1)
 	d.ShowDescription = false
 
-	l := list.New([]list.Item{}, d, 0, 0)
+	l := app.styles.newList(d, 0, 0)
 	l.KeyMap.CursorUp.SetKeys("k", "up")
 	l.KeyMap.CursorDown.SetKeys("j", "down")
 	l.KeyMap.Quit.SetKeys("ctrl+c")
@@ -76,14 +79,14 @@ func NewSidebar(app *App) *Sidebar {
 	l.SetShowHelp(false)
 	l.SetShowStatusBar(false)
 
-	pfp := NewImage(true, true, special)
+	pfp := NewImage(app, true, true)
 	pfp.SetSize(1, 1)
 
-	return &Sidebar{app: app, nav: &l, pfp: pfp}
+	return &Sidebar{app: app, nav: &l, pfp: pfp, styles: app.styles, delegate: d}
 }
 
 func (m *Sidebar) SetSize(w, h int) {
-	x, y := navStyle.GetFrameSize()
+	x, y := m.app.styles.nav.GetFrameSize()
 	m.w, m.h = w-x, h-y
 	m.nav.SetWidth(m.w)
 	m.nav.SetHeight(m.h)
@@ -99,9 +102,11 @@ func (m *Sidebar) SetActive(active bool) {
 
 func (m *Sidebar) navHeader() []list.Item {
 	items := []list.Item{}
-	if api.GetSigner(m.app.ctx.pk) != nil {
+	if m.app.client.GetSigner(m.app.ctx.pk) != nil {
 		items = append(items, &sidebarItem{name: "profile"})
 		items = append(items, &sidebarItem{name: "notifications"})
+		items = append(items, &sidebarItem{name: "add account"})
+		items = append(items, &sidebarItem{name: "sign out"})
 	}
 	items = append(items, &sidebarItem{name: "feed"})
 	items = append(items, &sidebarItem{name: "--channels---", value: "--channels--", icon: "🏠"})
@@ -109,11 +114,17 @@ func (m *Sidebar) navHeader() []list.Item {
 }
 
 func (m *Sidebar) Init() tea.Cmd {
-	log.Println("sidebar init")
+	slog.Debug("sidebar init")
 	var fid uint64
 	if m.app.ctx.signer != nil {
 		fid = m.app.ctx.signer.FID
 	}
+	m.accounts = nil
+	for _, s := range m.app.client.Signers(m.app.ctx.pk) {
+		if s.FID != fid {
+			m.accounts = append(m.accounts, s)
+		}
+	}
 	return tea.Batch(
 		m.nav.SetItems(m.navHeader()),
 		getChannelsCmd(m.app.client, true, fid),
@@ -148,8 +159,8 @@ func (m *Sidebar) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			currentItem := m.nav.SelectedItem().(*sidebarItem)
 			if currentItem.name == "profile" {
 				m.SetActive(false)
-				log.Println("profile selected")
-				fid := api.GetSigner(m.app.ctx.pk).FID
+				slog.Debug("profile selected")
+				fid := m.app.client.GetSigner(m.app.ctx.pk).FID
 				if fid == 0 {
 					return m, nil
 				}
@@ -161,12 +172,20 @@ func (m *Sidebar) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			if currentItem.name == "notifications" {
 				m.SetActive(false)
-				log.Println("notifications selected")
+				slog.Debug("notifications selected")
 				return m, tea.Sequence(m.app.FocusNotifications())
 			}
+			if currentItem.name == "sign out" {
+				m.SetActive(false)
+				return m, m.app.SignOut()
+			}
+			if currentItem.name == "add account" {
+				m.app.AddAccount()
+				return m, nil
+			}
 			if currentItem.name == "feed" {
 				m.SetActive(false)
-				log.Println("feed selected")
+				slog.Debug("feed selected")
 				return m, tea.Sequence(m.app.FocusFeed(), getDefaultFeedCmd(m.app.client, m.app.ctx.signer))
 			}
 			if currentItem.itype == "channel" {
@@ -198,22 +217,26 @@ func (m *Sidebar) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 func (m *Sidebar) View() string {
-	ss := navStyle
+	if m.styles != m.app.styles {
+		m.styles = m.app.styles
+		m.styles.restyleList(m.nav, &m.delegate)
+	}
+	ss := m.app.styles.nav
 	if m.account == nil {
-		return navStyle.Render(m.nav.View())
+		return ss.Render(m.nav.View())
 	}
 	if m.active {
-		ss = navStyle.BorderForeground(activeColor)
+		ss = ss.BorderForeground(m.app.theme.Accent)
 
 	}
 
-	accountStyle := NewStyle().
+	accountStyle := m.app.styles.New().
 		Border(lipgloss.RoundedBorder(), true, false, true).
 		Width(m.w).
 		MaxWidth(m.w).
 		Align(lipgloss.Center, lipgloss.Center).Margin(0).Padding(0)
 
-	account := accountStyle.Render(
+	block := []string{
 		lipgloss.JoinHorizontal(lipgloss.Center,
 			m.pfp.View(),
 			lipgloss.JoinVertical(lipgloss.Left,
@@ -221,7 +244,18 @@ func (m *Sidebar) View() string {
 				fmt.Sprintf("@%s", m.account.Username),
 			),
 		),
-	)
+	}
+	if len(m.accounts) > 0 {
+		others := []string{}
+		for _, s := range m.accounts {
+			others = append(others, fmt.Sprintf("@%s", s.Username))
+		}
+		others = append(others, "A to switch")
+		block = append(block, m.app.styles.New().Foreground(m.app.theme.Subtle).Render(
+			lipgloss.JoinVertical(lipgloss.Center, others...),
+		))
+	}
+	account := accountStyle.Render(lipgloss.JoinVertical(lipgloss.Center, block...))
 
 	return ss.Render(
 		lipgloss.JoinVertical(lipgloss.Top,
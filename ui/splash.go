@@ -2,8 +2,8 @@ package ui
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -20,7 +20,13 @@ var txt = `
   Terminally On Farcaster User Interface
 `
 
-var splashStyle = NewStyle().Align(lipgloss.Center).Margin(2, 2)
+// signinPollInterval is how often the splash checks whether a sign in
+// started from it has finished.
+const signinPollInterval = 2 * time.Second
+
+type signinPollMsg struct {
+	nonce string
+}
 
 type SplashView struct {
 	app     *App
@@ -29,19 +35,19 @@ type SplashView struct {
 	loading *Loading
 	active  bool
 	signin  bool
+	// nonce and code of the sign in in progress
+	nonce string
+	code  string
 }
 
 func NewSplashView(app *App) *SplashView {
 	x, y := lipgloss.Size(txt)
 	vp := viewport.New(x, y)
 	vp.SetContent(txt)
-	l := NewLoading()
+	l := NewLoading(app.styles)
 	l.SetActive(true)
 	info := viewport.New(20, 6)
 	info.SetContent("fetching feed...")
-	s := spinner.New()
-	s.Spinner = spinner.Dot
-	s.Style = NewStyle().Foreground(lipgloss.Color("205"))
 	return &SplashView{
 		vp: &vp, loading: l,
 		info: &info, active: true,
@@ -59,10 +65,20 @@ func (m *SplashView) SetActive(active bool) {
 func (m *SplashView) ShowSignin(v bool) {
 	m.loading.SetActive(!v)
 	m.signin = v
+	m.nonce, m.code = "", ""
 	if v {
 		m.info.SetContent("Press Enter to sign in")
+		if m.app.ctx.signer != nil {
+			m.info.SetContent("Press Enter to sign in to another account, or Esc to go back")
+		}
 	}
 }
+
+// SetSigninInfo replaces the sign in prompt.
+func (m *SplashView) SetSigninInfo(content string) {
+	m.info.SetContent(content)
+}
+
 func (m *SplashView) SetInfo(content string) {
 	if m.signin {
 		return
@@ -71,12 +87,80 @@ func (m *SplashView) SetInfo(content string) {
 }
 
 func (m *SplashView) SetSize(w, h int) {
-	x, y := splashStyle.GetFrameSize()
+	x, y := m.app.styles.splash.GetFrameSize()
 	m.vp.Width = w - x
 	m.vp.Height = h - y - 4
 	m.info.Width = w - x
 	m.info.Height = h - y - 8
 	m.loading.SetSize((w-x)/2, h)
+	if m.nonce != "" {
+		m.info.SetContent(m.signinInstructions())
+	}
+}
+
+func (m *SplashView) deviceURL() string {
+	return fmt.Sprintf("%s/device", m.app.cfg.BaseURL())
+}
+
+// signinInstructions shows the device code and, when there is room, a QR
+// code that opens the sign in page with the code filled in.
+func (m *SplashView) signinInstructions() string {
+	text := fmt.Sprintf(
+		"Visit %s and enter the code\n\n%s\n\nWaiting for sign in...",
+		m.deviceURL(), m.app.styles.New().Bold(true).Render(m.code),
+	)
+	qr, err := renderQR(m.app.styles.qr, fmt.Sprintf("%s?code=%s", m.deviceURL(), m.code))
+	if err != nil {
+		m.app.logger().Error("failed to render qr code", "error", err)
+		return text
+	}
+	full := lipgloss.JoinVertical(lipgloss.Center, qr, "", "Scan the code or", text)
+	if lipgloss.Height(full) > m.info.Height || lipgloss.Width(full) > m.info.Width {
+		return text
+	}
+	return full
+}
+
+func (m *SplashView) startSignin() tea.Cmd {
+	if m.app.nonces == nil {
+		m.info.SetContent("Sign in is not available here, run `tofui` to sign in")
+		return nil
+	}
+	// the nonce ties the sign in callback to this session
+	m.nonce, m.code = m.app.nonces.Issue(m.app.ctx.pk)
+	m.info.SetContent(m.signinInstructions())
+	return tea.Batch(
+		m.app.OpenURL(fmt.Sprintf("%s/signin?nonce=%s", m.app.cfg.BaseURL(), m.nonce)),
+		pollSignin(m.nonce),
+	)
+}
+
+func pollSignin(nonce string) tea.Cmd {
+	return tea.Tick(signinPollInterval, func(time.Time) tea.Msg {
+		return signinPollMsg{nonce: nonce}
+	})
+}
+
+func (m *SplashView) checkSignin(nonce string) tea.Cmd {
+	if !m.signin || nonce != m.nonce {
+		return nil
+	}
+	if fid, ok := m.app.nonces.Completed(nonce); ok {
+		m.nonce, m.code = "", ""
+		for _, s := range m.app.client.Signers(m.app.ctx.pk) {
+			if s.FID == fid {
+				return func() tea.Msg { return &UpdateSignerMsg{Signer: s} }
+			}
+		}
+		m.app.logger().Error("signed in account not found", "new_fid", fid)
+		return nil
+	}
+	if !m.app.nonces.Pending(nonce) {
+		m.nonce, m.code = "", ""
+		m.info.SetContent("The sign in code expired, press Enter for a new one")
+		return nil
+	}
+	return pollSignin(nonce)
 }
 
 func (m *SplashView) Init() tea.Cmd {
@@ -86,15 +170,23 @@ func (m *SplashView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if m.signin {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			if msg.String() == "enter" {
-				portPart := fmt.Sprintf(":%d", m.app.cfg.Server.HTTPPort)
-				if portPart == ":443" {
-					portPart = ""
+			if msg.String() == "esc" {
+				if m.app.ctx.signer != nil {
+					// adding another account was cancelled
+					m.ShowSignin(false)
+					m.SetActive(false)
+					return m, nil
+				}
+				// continue with another account after signing out
+				if next := m.app.client.GetSigner(m.app.ctx.pk); next != nil {
+					return m, m.app.UseAccount(next.FID)
 				}
-				u := fmt.Sprintf("%s/signin?pk=%s", m.app.cfg.BaseURL(), m.app.ctx.pk)
-				m.info.SetContent(fmt.Sprintf("Please sign in at %s", u))
-				return m, OpenURL(u)
 			}
+			if msg.String() == "enter" && m.nonce == "" {
+				return m, m.startSignin()
+			}
+		case signinPollMsg:
+			return m, m.checkSignin(msg.nonce)
 		}
 	}
 
@@ -105,10 +197,11 @@ func (m *SplashView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 func (m *SplashView) View() string {
-	return splashStyle.Render(
+	m.loading.SetStyles(m.app.styles)
+	return m.app.styles.splash.Render(
 		lipgloss.JoinVertical(lipgloss.Top,
 			m.vp.View(),
-			lipgloss.NewStyle().MarginTop(1).Render(m.loading.View()),
+			m.app.styles.New().MarginTop(1).Render(m.loading.View()),
 			m.info.View(),
 		),
 	)
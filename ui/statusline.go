@@ -3,49 +3,30 @@ package ui
 import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/mistakenelf/teacup/statusbar"
+	"github.com/charmbracelet/x/ansi"
 )
 
-var statusStyle = NewStyle().BorderTop(true).BorderStyle(lipgloss.RoundedBorder())
-
+// StatusLine shows the current view, any notice and short help. It draws
+// itself rather than using a statusbar component so its colors come from
+// the session's renderer.
 type StatusLine struct {
-	app  *App
-	sb   statusbar.Model
-	help *HelpView
-	full bool
+	app   *App
+	help  *HelpView
+	width int
+	full  bool
 }
 
 func NewStatusLine(app *App) *StatusLine {
-	sb := statusbar.New(
-		statusbar.ColorConfig{
-			Foreground: lipgloss.AdaptiveColor{Dark: "#ffffff", Light: "#ffffff"},
-			Background: lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#483285"},
-		},
-		statusbar.ColorConfig{
-			// Foreground: lipgloss.AdaptiveColor{Light: "#ffffff", Dark: "#ffffff"},
-			// Background: lipgloss.AdaptiveColor{Light: "#3c3836", Dark: "#3c3836"},
-		},
-		statusbar.ColorConfig{
-			// Foreground: lipgloss.AdaptiveColor{Light: "#ffffff", Dark: "#ffffff"},
-			// Background: lipgloss.AdaptiveColor{Light: "#A550DF", Dark: "#A550DF"},
-		},
-		statusbar.ColorConfig{
-			// Foreground: lipgloss.AdaptiveColor{Light: "#ffffff", Dark: "#ffffff"},
-			// Background: lipgloss.AdaptiveColor{Light: "#6124DF", Dark: "#6124DF"},
-		},
-	)
 	return &StatusLine{
-		sb:   sb,
 		app:  app,
-		help: NewHelpView(app, GlobalKeyMap),
+		help: NewHelpView(app, app.keys),
 		full: false,
 	}
 }
 
 func (m *StatusLine) SetSize(width, height int) {
-	fx, _ := statusStyle.GetFrameSize()
-	m.sb.SetSize(width - fx)
-	m.sb.Height = 1 
+	fx, _ := m.app.styles.status.GetFrameSize()
+	m.width = width - fx
 }
 
 func (m *StatusLine) Init() tea.Cmd {
@@ -53,11 +34,21 @@ func (m *StatusLine) Init() tea.Cmd {
 }
 
 func (m *StatusLine) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	m.sb.SetContent(m.app.navname, "", "", m.help.ShortView())
-	_, cmd := m.sb.Update(msg)
-	return m, cmd
+	return m, nil
 }
 
 func (m *StatusLine) View() string {
-	return statusStyle.Render(m.sb.View())
+	s := m.app.styles
+	name := s.New().
+		Foreground(m.app.theme.StatusFg).
+		Background(m.app.theme.StatusBg).
+		Padding(0, 1).
+		Render(ansi.Truncate(m.app.navname, 30, "..."))
+	help := s.New().Padding(0, 1).Render(m.help.ShortView())
+
+	w := max(0, m.width-lipgloss.Width(name)-lipgloss.Width(help))
+	notice := s.New().Padding(0, 1).Width(w).
+		Render(ansi.Truncate(m.app.notice, max(0, w-3), "..."))
+
+	return s.status.Render(lipgloss.JoinHorizontal(lipgloss.Top, name, notice, help))
 }
@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"reflect"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// styles belong to one App and are made with its session's renderer, so
+// each terminal is drawn with the color profile and background it reported.
+type styles struct {
+	r     *lipgloss.Renderer
+	theme *Theme
+
+	main          lipgloss.Style
+	cast          lipgloss.Style
+	castHeader    lipgloss.Style
+	header        lipgloss.Style
+	displayName   lipgloss.Style
+	username      lipgloss.Style
+	content       lipgloss.Style
+	feed          lipgloss.Style
+	channelHeader lipgloss.Style
+	nav           lipgloss.Style
+	splash        lipgloss.Style
+	status        lipgloss.Style
+	dialogBox     lipgloss.Style
+	qr            lipgloss.Style
+
+	md *glamour.TermRenderer
+}
+
+func newStyles(r *lipgloss.Renderer, t *Theme) *styles {
+	return &styles{
+		r:     r,
+		theme: t,
+
+		main:          r.NewStyle().Margin(0).Padding(0).Border(lipgloss.RoundedBorder()),
+		cast:          r.NewStyle(),
+		castHeader:    r.NewStyle().Margin(1, 1).Align(lipgloss.Top),
+		header:        r.NewStyle().BorderBottom(true),
+		displayName:   r.NewStyle().MarginRight(5).Foreground(t.Accent),
+		username:      r.NewStyle(),
+		content:       r.NewStyle(),
+		feed:          r.NewStyle().Margin(2, 2).Align(lipgloss.Center),
+		channelHeader: r.NewStyle().Margin(1, 1).Align(lipgloss.Top).Border(lipgloss.RoundedBorder()),
+		nav:           r.NewStyle().Margin(2, 2, 0, 0).BorderRight(true).BorderStyle(lipgloss.RoundedBorder()),
+		splash:        r.NewStyle().Align(lipgloss.Center).Margin(2, 2),
+		status:        r.NewStyle().BorderTop(true).BorderStyle(lipgloss.RoundedBorder()),
+		dialogBox:     r.NewStyle(),
+		qr: r.NewStyle().
+			Foreground(lipgloss.Color("#000000")).
+			Background(lipgloss.Color("#ffffff")),
+	}
+}
+
+// New starts a style drawn for this session's terminal.
+func (s *styles) New() lipgloss.Style {
+	return s.r.NewStyle()
+}
+
+// colorString resolves c against the session's background, for bubbles
+// that take colors as strings.
+func (s *styles) colorString(c lipgloss.TerminalColor) string {
+	switch c := c.(type) {
+	case lipgloss.Color:
+		return string(c)
+	case lipgloss.AdaptiveColor:
+		if s.r.HasDarkBackground() {
+			return c.Dark
+		}
+		return c.Light
+	}
+	return ""
+}
+
+// markdown returns the renderer for cast text, built on first use. The auto
+// style is resolved against the session's background, not the server's.
+func (s *styles) markdown() *glamour.TermRenderer {
+	if s.md != nil {
+		return s.md
+	}
+	style := s.theme.Markdown
+	switch {
+	case style != glamour.AutoStyle:
+	case s.r.ColorProfile() == termenv.Ascii:
+		style = glamour.NoTTYStyle
+	case s.r.HasDarkBackground():
+		style = glamour.DarkStyle
+	default:
+		style = glamour.LightStyle
+	}
+	md, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(style),
+		glamour.WithColorProfile(s.r.ColorProfile()),
+		glamour.WithWordWrap(80),
+	)
+	if err != nil {
+		md, _ = glamour.NewTermRenderer(
+			glamour.WithStandardStyle(glamour.DarkStyle),
+			glamour.WithColorProfile(s.r.ColorProfile()),
+			glamour.WithWordWrap(80),
+		)
+	}
+	s.md = md
+	return md
+}
+
+// adopt points every style in the struct v points to at the session's
+// renderer. bubbles components build their styles with lipgloss's default
+// renderer, which is the server's terminal over SSH.
+func (s *styles) adopt(v any) {
+	rv := reflect.ValueOf(v).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		if st, ok := f.Interface().(lipgloss.Style); ok {
+			f.Set(reflect.ValueOf(st.Renderer(s.r)))
+		}
+	}
+}
+
+// newDelegate returns a list delegate that marks the selected item in the
+// theme's accent.
+func (s *styles) newDelegate() list.DefaultDelegate {
+	d := list.NewDefaultDelegate()
+	s.styleDelegate(&d)
+	return d
+}
+
+func (s *styles) styleDelegate(d *list.DefaultDelegate) {
+	d.Styles = list.NewDefaultItemStyles()
+	s.adopt(&d.Styles)
+	d.Styles.SelectedTitle = d.Styles.SelectedTitle.Foreground(s.theme.Accent).BorderForeground(s.theme.Accent)
+	d.Styles.SelectedDesc = d.Styles.SelectedDesc.Foreground(s.theme.Accent).BorderForeground(s.theme.Accent)
+}
+
+func (s *styles) newList(d list.ItemDelegate, w, h int) list.Model {
+	l := list.New([]list.Item{}, d, w, h)
+	s.styleList(&l)
+	return l
+}
+
+func (s *styles) styleList(l *list.Model) {
+	l.Styles = list.DefaultStyles()
+	s.adopt(&l.Styles)
+	l.Styles.Title = l.Styles.Title.Background(s.theme.Accent)
+}
+
+// restyleList redraws l and its delegate d with these styles, for lists
+// built before the theme changed.
+func (s *styles) restyleList(l *list.Model, d *list.DefaultDelegate) {
+	s.styleDelegate(d)
+	l.SetDelegate(*d)
+	s.styleList(l)
+}
+
+func (s *styles) newHelp() help.Model {
+	h := help.New()
+	s.adopt(&h.Styles)
+	return h
+}
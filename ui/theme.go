@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/treethought/tofui/config"
+)
+
+// Theme is the palette components draw with. Each App has its own copy so
+// themes can be switched per session.
+type Theme struct {
+	Name string
+	// Accent marks focused borders, display names and titles
+	Accent lipgloss.TerminalColor
+	// Subtle is for dividers and whitespace
+	Subtle lipgloss.TerminalColor
+	// Special frames images
+	Special      lipgloss.TerminalColor
+	Error        lipgloss.TerminalColor
+	StatusFg     lipgloss.TerminalColor
+	StatusBg     lipgloss.TerminalColor
+	HeaderBorder lipgloss.TerminalColor
+	SelectedFg   lipgloss.TerminalColor
+	SelectedBg   lipgloss.TerminalColor
+	// Markdown is the glamour style cast text is rendered with
+	Markdown string
+}
+
+// themeNames are the built in themes, in the order the theme key cycles
+// through them.
+var themeNames = []string{"auto", "dark", "light", "high-contrast"}
+
+func builtinTheme(name string) (*Theme, bool) {
+	switch name {
+	case "", "auto":
+		return &Theme{
+			Name:         "auto",
+			Accent:       lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"},
+			Subtle:       lipgloss.AdaptiveColor{Light: "#D9DCCF", Dark: "#383838"},
+			Special:      lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"},
+			Error:        lipgloss.Color("#ff0000"),
+			StatusFg:     lipgloss.Color("#ffffff"),
+			StatusBg:     lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#483285"},
+			HeaderBorder: lipgloss.Color("240"),
+			SelectedFg:   lipgloss.Color("229"),
+			SelectedBg:   lipgloss.Color("57"),
+			Markdown:     glamour.AutoStyle,
+		}, true
+	case "dark":
+		return &Theme{
+			Name:         "dark",
+			Accent:       lipgloss.Color("#7D56F4"),
+			Subtle:       lipgloss.Color("#383838"),
+			Special:      lipgloss.Color("#73F59F"),
+			Error:        lipgloss.Color("#ff5f5f"),
+			StatusFg:     lipgloss.Color("#ffffff"),
+			StatusBg:     lipgloss.Color("#483285"),
+			HeaderBorder: lipgloss.Color("240"),
+			SelectedFg:   lipgloss.Color("229"),
+			SelectedBg:   lipgloss.Color("57"),
+			Markdown:     glamour.DarkStyle,
+		}, true
+	case "light":
+		return &Theme{
+			Name:         "light",
+			Accent:       lipgloss.Color("#874BFD"),
+			Subtle:       lipgloss.Color("#D9DCCF"),
+			Special:      lipgloss.Color("#43BF6D"),
+			Error:        lipgloss.Color("#d70000"),
+			StatusFg:     lipgloss.Color("#ffffff"),
+			StatusBg:     lipgloss.Color("#F25D94"),
+			HeaderBorder: lipgloss.Color("250"),
+			SelectedFg:   lipgloss.Color("#ffffff"),
+			SelectedBg:   lipgloss.Color("#874BFD"),
+			Markdown:     glamour.LightStyle,
+		}, true
+	case "high-contrast":
+		return &Theme{
+			Name:         "high-contrast",
+			Accent:       lipgloss.Color("11"),
+			Subtle:       lipgloss.Color("15"),
+			Special:      lipgloss.Color("14"),
+			Error:        lipgloss.Color("9"),
+			StatusFg:     lipgloss.Color("0"),
+			StatusBg:     lipgloss.Color("11"),
+			HeaderBorder: lipgloss.Color("15"),
+			SelectedFg:   lipgloss.Color("0"),
+			SelectedBg:   lipgloss.Color("14"),
+			Markdown:     glamour.DarkStyle,
+		}, true
+	}
+	return nil, false
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func parseColor(s string) (lipgloss.Color, error) {
+	if colorPattern.MatchString(s) {
+		return lipgloss.Color(s), nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(s), nil
+	}
+	return "", fmt.Errorf("%q is not a hex color or ANSI color number", s)
+}
+
+// NewTheme builds the configured theme, the named built in theme with any
+// configured colors applied over it.
+func NewTheme(cfg config.Theme) (*Theme, error) {
+	t, ok := builtinTheme(cfg.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q, expected one of %v", cfg.Name, themeNames)
+	}
+	c := cfg.Colors
+	var errs []error
+	custom := false
+	for _, o := range []struct {
+		name  string
+		value string
+		color *lipgloss.TerminalColor
+	}{
+		{"accent", c.Accent, &t.Accent},
+		{"subtle", c.Subtle, &t.Subtle},
+		{"special", c.Special, &t.Special},
+		{"error", c.Error, &t.Error},
+		{"status_fg", c.StatusFg, &t.StatusFg},
+		{"status_bg", c.StatusBg, &t.StatusBg},
+		{"header_border", c.HeaderBorder, &t.HeaderBorder},
+		{"selected_fg", c.SelectedFg, &t.SelectedFg},
+		{"selected_bg", c.SelectedBg, &t.SelectedBg},
+	} {
+		if o.value == "" {
+			continue
+		}
+		color, err := parseColor(o.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("theme.colors.%s: %w", o.name, err))
+			continue
+		}
+		*o.color = color
+		custom = true
+	}
+	if c.Markdown != "" {
+		if _, ok := glamour.DefaultStyles[c.Markdown]; !ok && c.Markdown != glamour.AutoStyle {
+			errs = append(errs, fmt.Errorf("theme.colors.markdown: unknown style %q", c.Markdown))
+		}
+		t.Markdown = c.Markdown
+		custom = true
+	}
+	if custom {
+		t.Name = "custom"
+	}
+	return t, errors.Join(errs...)
+}
+
+// themes returns the configured theme followed by the built in themes it
+// isn't, which the theme key cycles through.
+func themes(cfg config.Theme) []*Theme {
+	start, err := NewTheme(cfg)
+	if err != nil {
+		start, _ = builtinTheme("auto")
+	}
+	list := []*Theme{start}
+	for _, name := range themeNames {
+		if name != start.Name {
+			t, _ := builtinTheme(name)
+			list = append(list, t)
+		}
+	}
+	return list
+}
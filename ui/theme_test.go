@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/treethought/tofui/api"
+	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
+)
+
+func TestTheme(t *testing.T) {
+	th, err := NewTheme(config.Theme{Name: "light", Colors: config.ThemeColors{Accent: "#ff8700", SelectedBg: "238"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.Name != "custom" || th.Accent != lipgloss.Color("#ff8700") || th.SelectedBg != lipgloss.Color("238") {
+		t.Errorf("expected overrides applied, got %+v", th)
+	}
+	if th.Subtle != lipgloss.Color("#D9DCCF") {
+		t.Errorf("expected light colors to be kept, got %v", th.Subtle)
+	}
+
+	if _, err := NewTheme(config.Theme{Name: "solarized"}); err == nil {
+		t.Error("expected an unknown theme to be an error")
+	}
+	_, err = NewTheme(config.Theme{Colors: config.ThemeColors{Error: "red", Markdown: "neon"}})
+	for _, want := range []string{"theme.colors.error", `unknown style "neon"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+
+	cfg := *testCfg
+	cfg.Theme = config.Theme{Name: "dark"}
+	a := NewApp(&cfg, api.NewClient(&cfg, db.NewMemoryStore(&cfg)), &AppContext{}, false)
+	var seen []string
+	for range themeNames {
+		seen = append(seen, a.theme.Name)
+		a.NextTheme()
+	}
+	if want := "dark auto light high-contrast"; strings.Join(seen, " ") != want {
+		t.Errorf("expected to cycle %s, got %v", want, seen)
+	}
+	if a.theme.Name != "dark" || a.notice != "theme: dark" {
+		t.Errorf("expected to wrap back to dark, got %s with notice %q", a.theme.Name, a.notice)
+	}
+
+	// lists and loading bars built with the last theme are redrawn
+	_ = a.sidebar.View()
+	if fg := a.sidebar.delegate.Styles.SelectedTitle.GetForeground(); fg != a.theme.Accent {
+		t.Errorf("expected sidebar selection in the dark accent, got %v", fg)
+	}
+	a.NextTheme()
+	_ = a.sidebar.View()
+	_ = a.splash.View()
+	if fg := a.sidebar.delegate.Styles.SelectedTitle.GetForeground(); fg != a.theme.Accent {
+		t.Errorf("expected sidebar selection to follow the theme to %v, got %v", a.theme.Accent, fg)
+	}
+	if want := a.styles.colorString(a.theme.Accent); a.splash.loading.prog.FullColor != want {
+		t.Errorf("expected loading bar to follow the theme to %s, got %s", want, a.splash.loading.prog.FullColor)
+	}
+}
@@ -1,7 +1,7 @@
 package ui
 
 import (
-	"log"
+	"log/slog"
 	"os/exec"
 	"runtime"
 
@@ -16,9 +16,19 @@ var (
 	EmojiPerson    = "👤"
 )
 
+// OpenURL opens url in a browser, unless the app is served over SSH where
+// the browser would be launched on the server.
+func (a *App) OpenURL(url string) tea.Cmd {
+	if a.ctx.s != nil {
+		a.logger().Debug("not opening url for ssh session", "url", url)
+		return nil
+	}
+	return OpenURL(url)
+}
+
 func OpenURL(url string) tea.Cmd {
 	return func() tea.Msg {
-		log.Println("Opening URL: ", url)
+		slog.Debug("opening url", "url", url)
 		var cmd string
 		var args []string
 
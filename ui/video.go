@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/treethought/tofui/db"
+)
+
+// videoPlaceholder is drawn for videos without a poster frame.
+const videoPlaceholder = "▶ video"
+
+// defaultVideoPlayer plays videos when images.video_player isn't set.
+const defaultVideoPlayer = "mpv"
+
+var videoExts = []string{".mp4", ".m3u8", ".webm", ".mov"}
+
+// isVideo reports whether u is a video ffmpeg and players can fetch, going
+// by its extension. Only http urls are accepted so an embed can't name a
+// local file.
+func isVideo(u string) bool {
+	p, err := url.Parse(u)
+	if err != nil || (p.Scheme != "http" && p.Scheme != "https") {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(p.Path))
+	for _, e := range videoExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// videoPoster grabs the first frame of the video with ffmpeg, when it's
+// installed, and caches it like any other image.
+func videoPoster(store db.Store, u string) ([]byte, error) {
+	key := []byte(fmt.Sprintf("img:%s", u))
+	if cached, err := store.Get(key); err == nil {
+		return cached, nil
+	}
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 15*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, ffmpeg,
+		"-loglevel", "error",
+		"-protocol_whitelist", "http,https,tcp,tls,crypto",
+		"-i", u,
+		"-frames:v", "1",
+		"-f", "image2pipe", "-vcodec", "png", "-",
+	).Output()
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("ffmpeg returned no frame")
+	}
+	if err := store.Set(key, out); err != nil {
+		slog.Error("error saving video poster", "error", err)
+	}
+	return out, nil
+}
+
+// PlayVideo opens u in the configured video player, or the browser when
+// the player can't be started. Over SSH the player would start on the
+// server, so the url is shown to copy instead.
+func (a *App) PlayVideo(u string) tea.Cmd {
+	if a.ctx.s != nil {
+		a.notice = fmt.Sprintf("video: %s", u)
+		return nil
+	}
+	player := strings.Fields(a.cfg.Images.VideoPlayer)
+	if len(player) == 0 {
+		player = []string{defaultVideoPlayer}
+	}
+	return func() tea.Msg {
+		cmd := exec.Command(player[0], append(player[1:], u)...)
+		if err := cmd.Start(); err != nil {
+			slog.Debug("failed to start video player", "player", player[0], "error", err)
+			return OpenURL(u)()
+		}
+		go func() { _ = cmd.Wait() }()
+		return nil
+	}
+}
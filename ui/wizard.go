@@ -0,0 +1,295 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/treethought/tofui/config"
+)
+
+type wizardStep int
+
+const (
+	stepOverwrite wizardStep = iota
+	stepFields
+	stepVerify
+	stepConfirm
+)
+
+// wizardStyles are made with the renderer of the terminal the wizard runs
+// in.
+type wizardStyles struct {
+	title   lipgloss.Style
+	label   lipgloss.Style
+	focus   lipgloss.Style
+	help    lipgloss.Style
+	err     lipgloss.Style
+	spinner lipgloss.Style
+}
+
+func newWizardStyles(r *lipgloss.Renderer) wizardStyles {
+	accent := lipgloss.Color("205")
+	return wizardStyles{
+		title:   r.NewStyle().Bold(true).Foreground(accent),
+		label:   r.NewStyle().Width(14),
+		focus:   r.NewStyle().Width(14).Foreground(accent),
+		help:    r.NewStyle().Faint(true),
+		err:     r.NewStyle().Foreground(lipgloss.Color("#ff0000")),
+		spinner: r.NewStyle().Foreground(accent),
+	}
+}
+
+type apiKeyVerifiedMsg struct {
+	err error
+}
+
+type wizardField struct {
+	label    string
+	input    textinput.Model
+	validate func(string) error
+	set      func(*config.Config, string)
+}
+
+// InitWizard walks through creating a config file, checking the API key
+// with Neynar before anything is written. Run it as a program and read
+// Result once it exits.
+type InitWizard struct {
+	path     string
+	defaults config.Config
+	step     wizardStep
+	fields   []*wizardField
+	focus    int
+	verify   func(*config.Config) error
+	spinner  spinner.Model
+	styles   wizardStyles
+	err      error
+	cfg      *config.Config
+	done     bool
+}
+
+// NewInitWizard prefills the form from defaults. When exists is set the
+// user must first agree to replace the file at path.
+func NewInitWizard(path string, defaults *config.Config, exists bool, verify func(*config.Config) error) *InitWizard {
+	// the wizard only runs locally, so the default renderer is the user's
+	st := newWizardStyles(lipgloss.DefaultRenderer())
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = st.spinner
+	w := &InitWizard{
+		path:     path,
+		defaults: *defaults,
+		verify:   verify,
+		spinner:  s,
+		styles:   st,
+		fields:   newWizardFields(defaults),
+	}
+	if !exists {
+		w.step = stepFields
+	}
+	w.setFocus(0)
+	return w
+}
+
+func newWizardFields(d *config.Config) []*wizardField {
+	field := func(label, hint, value string, validate func(string) error, set func(*config.Config, string)) *wizardField {
+		ti := textinput.New()
+		ti.Prompt = ""
+		ti.Placeholder = hint
+		ti.SetValue(value)
+		ti.Width = 60
+		return &wizardField{label: label, input: ti, validate: validate, set: set}
+	}
+	port := func(p int) string {
+		if p == 0 {
+			return ""
+		}
+		return strconv.Itoa(p)
+	}
+	apiKey := field("API key", "found at https://dev.neynar.com", d.Neynar.APIKey, required,
+		func(c *config.Config, v string) { c.Neynar.APIKey = v })
+	apiKey.input.EchoMode = textinput.EchoPassword
+	apiKey.input.EchoCharacter = '•'
+
+	return []*wizardField{
+		field("Client ID", "found at https://dev.neynar.com/app", d.Neynar.ClientID, required,
+			func(c *config.Config, v string) { c.Neynar.ClientID = v }),
+		apiKey,
+		field("HTTP port", "serves the sign in page", port(d.Server.HTTPPort), validPort,
+			func(c *config.Config, v string) { c.Server.HTTPPort, _ = strconv.Atoi(v) }),
+		field("SSH port", "used by `tofui ssh`", port(d.Server.SSHPort), validPort,
+			func(c *config.Config, v string) { c.Server.SSHPort, _ = strconv.Atoi(v) }),
+		field("DB directory", "where the cache and sign ins are kept", d.DB.Dir, required,
+			func(c *config.Config, v string) { c.DB.Dir = filepath.Clean(v) }),
+		field("Log file", "\"-\" logs to stderr", d.Log.Path, required,
+			func(c *config.Config, v string) { c.Log.Path = v }),
+	}
+}
+
+func required(v string) error {
+	if v == "" {
+		return errors.New("required")
+	}
+	return nil
+}
+
+func validPort(v string) error {
+	p, err := strconv.Atoi(v)
+	if err != nil || p < 1 || p > 65535 {
+		return errors.New("must be a port between 1 and 65535")
+	}
+	return nil
+}
+
+// Result returns the config the user confirmed, or false if they quit
+// before it was verified and confirmed.
+func (w *InitWizard) Result() (*config.Config, bool) {
+	return w.cfg, w.done
+}
+
+func (w *InitWizard) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (w *InitWizard) setFocus(i int) tea.Cmd {
+	w.fields[w.focus].input.Blur()
+	w.focus = i
+	return w.fields[i].input.Focus()
+}
+
+func (w *InitWizard) value(i int) string {
+	return strings.TrimSpace(w.fields[i].input.Value())
+}
+
+// build checks every field, focusing the first that is invalid.
+func (w *InitWizard) build() (*config.Config, tea.Cmd) {
+	c := w.defaults
+	for i, f := range w.fields {
+		if err := f.validate(w.value(i)); err != nil {
+			w.err = fmt.Errorf("%s %w", f.label, err)
+			return nil, w.setFocus(i)
+		}
+		f.set(&c, w.value(i))
+	}
+	return &c, nil
+}
+
+func (w *InitWizard) verifyCmd(c *config.Config) tea.Cmd {
+	return func() tea.Msg {
+		return apiKeyVerifiedMsg{err: w.verify(c)}
+	}
+}
+
+func (w *InitWizard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case apiKeyVerifiedMsg:
+		if msg.err != nil {
+			w.err = fmt.Errorf("could not verify the API key: %w", msg.err)
+			w.step = stepFields
+			return w, w.setFocus(1)
+		}
+		w.step = stepConfirm
+		return w, nil
+	case spinner.TickMsg:
+		if w.step != stepVerify {
+			return w, nil
+		}
+		var cmd tea.Cmd
+		w.spinner, cmd = w.spinner.Update(msg)
+		return w, cmd
+	case tea.KeyMsg:
+		if msg.Type == tea.KeyCtrlC || msg.Type == tea.KeyEsc {
+			return w, tea.Quit
+		}
+		switch w.step {
+		case stepOverwrite:
+			if msg.String() == "y" {
+				w.step = stepFields
+				return w, nil
+			}
+			if msg.String() == "n" || msg.Type == tea.KeyEnter {
+				return w, tea.Quit
+			}
+			return w, nil
+		case stepVerify:
+			return w, nil
+		case stepConfirm:
+			switch msg.String() {
+			case "y":
+				w.done = true
+				return w, tea.Quit
+			case "n":
+				w.step = stepFields
+				return w, nil
+			}
+			return w, nil
+		}
+		switch msg.String() {
+		case "enter":
+			w.err = nil
+			if w.focus < len(w.fields)-1 {
+				return w, w.setFocus(w.focus + 1)
+			}
+			c, cmd := w.build()
+			if c == nil {
+				return w, cmd
+			}
+			w.cfg = c
+			w.step = stepVerify
+			return w, tea.Batch(w.spinner.Tick, w.verifyCmd(c))
+		case "tab", "down":
+			return w, w.setFocus((w.focus + 1) % len(w.fields))
+		case "shift+tab", "up":
+			return w, w.setFocus((w.focus + len(w.fields) - 1) % len(w.fields))
+		}
+	}
+	var cmd tea.Cmd
+	w.fields[w.focus].input, cmd = w.fields[w.focus].input.Update(msg)
+	return w, cmd
+}
+
+func (w *InitWizard) View() string {
+	b := &strings.Builder{}
+	b.WriteString(w.styles.title.Render("tofui setup"))
+	b.WriteString("\n\n")
+
+	switch w.step {
+	case stepOverwrite:
+		fmt.Fprintf(b, "A config file already exists at %s\n\n", w.path)
+		b.WriteString("Replace it? (y/N)")
+		return b.String()
+	case stepVerify:
+		fmt.Fprintf(b, "%s checking your API key with Neynar...", w.spinner.View())
+		return b.String()
+	case stepConfirm:
+		b.WriteString("API key verified.\n\n")
+		fmt.Fprintf(b, "Write config to %s? (y/n)\n\n", w.path)
+		b.WriteString(w.styles.help.Render(fmt.Sprintf(
+			"Add '%s' to your Neynar app's authorized origins to sign in", w.cfg.BaseURL(),
+		)))
+		return b.String()
+	}
+
+	b.WriteString("To use tofui locally you will need to create a Neynar app.\n\n")
+	for i, f := range w.fields {
+		label := w.styles.label.Render(f.label)
+		if i == w.focus {
+			label = w.styles.focus.Render(f.label)
+		}
+		fmt.Fprintf(b, "%s %s\n", label, f.input.View())
+	}
+	b.WriteString("\n")
+	if w.err != nil {
+		b.WriteString(w.styles.err.Render(w.err.Error()))
+		b.WriteString("\n")
+	}
+	b.WriteString(w.styles.help.Render("enter next • shift+tab back • esc cancel"))
+	return b.String()
+}
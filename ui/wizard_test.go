@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/treethought/tofui/api"
+	"github.com/treethought/tofui/api/neynartest"
+	"github.com/treethought/tofui/config"
+	"github.com/treethought/tofui/db"
+)
+
+func TestInitWizard(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	defaults := &config.Config{}
+	defaults.Neynar.BaseUrl = testServer.URL
+	defaults.Server.HTTPPort = 4200
+	defaults.Server.SSHPort = 42069
+	defaults.DB.Dir = filepath.Join(dir, "db")
+	defaults.Log.Path = filepath.Join(dir, "tofui.log")
+	verify := func(c *config.Config) error {
+		client := api.NewClient(c, db.NewMemoryStore(c))
+		defer client.Close()
+		return client.CheckAPIKey()
+	}
+	w := NewInitWizard(path, defaults, true, verify)
+
+	var cmd tea.Cmd
+	send := func(msgs ...tea.Msg) {
+		for _, msg := range msgs {
+			_, cmd = w.Update(msg)
+		}
+	}
+	typed := func(s string) tea.Msg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	// runs the verification, which is batched with the spinner
+	verified := func() tea.Msg {
+		for _, c := range cmd().(tea.BatchMsg) {
+			if msg, ok := c().(apiKeyVerifiedMsg); ok {
+				return msg
+			}
+		}
+		t.Fatal("expected the API key to be verified")
+		return nil
+	}
+
+	if !strings.Contains(w.View(), "Replace it?") {
+		t.Fatal("expected confirmation before replacing an existing config")
+	}
+	send(typed("y"), typed("client-id"), enter, typed("wrong-key"))
+	if strings.Contains(w.View(), "wrong-key") {
+		t.Error("expected the API key to be masked")
+	}
+	send(enter, enter, enter, enter, enter)
+	send(verified())
+	if _, ok := w.Result(); ok || !strings.Contains(w.View(), "could not verify") {
+		t.Fatalf("expected a rejected key to be reported, got\n%s", w.View())
+	}
+
+	for range "wrong-key" {
+		send(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	send(typed(neynartest.APIKey), enter, enter, enter, enter, enter)
+	send(verified())
+	if !strings.Contains(w.View(), "Write config to "+path+"?") {
+		t.Fatalf("expected to be asked to write the config, got\n%s", w.View())
+	}
+	send(typed("y"))
+	c, ok := w.Result()
+	if !ok {
+		t.Fatal("expected the config to be confirmed")
+	}
+	if c.Neynar.ClientID != "client-id" || c.Neynar.APIKey != neynartest.APIKey || c.Server.SSHPort != 42069 {
+		t.Errorf("unexpected config %+v", c)
+	}
+}